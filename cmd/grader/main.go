@@ -0,0 +1,129 @@
+// Command grader runs a suite file against a running service and prints a report,
+// without a database. Students can check their work before submitting it, and staff
+// can try a suite out before publishing it.
+//
+//	go run ./cmd/grader -suite blog.yaml -url http://localhost:8080
+//
+// It exits with 1 if anything didn't pass and with 2 if the suite couldn't be run.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+)
+
+func main() {
+	suitePath := flag.String("suite", "", "suite file to run, YAML or JSON")
+	baseUrl := flag.String("url", "", "base URL of the service to grade, e.g. http://localhost:8080")
+	concurrency := flag.Int("concurrency", 1, "how many scenarios may run at the same time")
+	testTimeout := flag.Duration("timeout", 10*time.Second, "time limit of a single test, 0 for none")
+	runTimeout := flag.Duration("run-timeout", 10*time.Minute, "time limit of the whole run, 0 for none")
+	verbose := flag.Bool("v", false, "list passed tests too")
+	flag.Parse()
+
+	if *suitePath == "" || *baseUrl == "" {
+		fmt.Fprintln(os.Stderr, "usage: grader -suite <file> -url <base url> [-concurrency n] [-timeout d] [-run-timeout d] [-v]")
+		os.Exit(2)
+	}
+
+	file, err := os.Open(*suitePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open suite: %v\n", err)
+		os.Exit(2)
+	}
+	suite, err := grader.ReadSuite(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read suite: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Interrupting the run records what's left as cancelled, so there's still a report.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	gd := grader.NewGrader(strings.TrimSuffix(*baseUrl, "/"), 0)
+	gd.Concurrency = *concurrency
+	gd.TestTimeout = *testTimeout
+	gd.RunTimeout = *runTimeout
+
+	sink := grader.NewMemorySink()
+	result, err := gd.GradeSuite(ctx, suite, sink)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to grade suite: %v\n", err)
+		os.Exit(2)
+	}
+	result, err = sink.ProjectResult(result.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to collect results: %v\n", err)
+		os.Exit(2)
+	}
+
+	report(os.Stdout, result, *verbose)
+	if result.Status != grader.StatusPassed {
+		os.Exit(1)
+	}
+}
+
+// report prints a result tree, one line per section, scenario and test. Passed tests
+// are left out unless verbose is set.
+func report(w io.Writer, result *grader.ProjectResult, verbose bool) {
+	fmt.Fprintf(w, "%s %s  %s\n", mark(result.Status), result.ProjectName, score(result.Score, result.MaxScore))
+	for _, sec := range result.Sections {
+		fmt.Fprintf(w, "  %s %s  %s\n", mark(sec.Status), sec.SectionName, score(sec.Score, sec.MaxScore))
+		if sec.Status == grader.StatusSkipped || sec.Status == grader.StatusCancelled {
+			fmt.Fprintf(w, "       %s\n", sec.Message)
+		}
+		for _, scn := range sec.Scenarios {
+			fmt.Fprintf(w, "    %s %s  %s\n", mark(scn.Status), scn.ScenarioName, score(scn.Score, scn.MaxScore))
+			if scn.Status == grader.StatusSkipped || scn.Status == grader.StatusCancelled {
+				fmt.Fprintf(w, "         %s\n", scn.Message)
+			}
+			for _, test := range scn.Tests {
+				if test.Status == grader.StatusPassed && !verbose {
+					continue
+				}
+				fmt.Fprintf(w, "      %s %s\n", mark(test.Status), test.TestName)
+				if test.Status != grader.StatusPassed && test.Message != "" {
+					fmt.Fprintf(w, "           %s\n", strings.ReplaceAll(test.Message, "\n", "\n           "))
+				}
+				for _, a := range test.Assertions {
+					if a.Passed {
+						if verbose {
+							fmt.Fprintf(w, "           ok     %s\n", a.Expr)
+						}
+						continue
+					}
+					fmt.Fprintf(w, "           not ok %s: %s\n", a.Expr, a.Message)
+				}
+			}
+		}
+	}
+	fmt.Fprintf(w, "\n%s\n", result.Message)
+}
+
+func mark(status grader.GradingStatus) string {
+	switch status {
+	case grader.StatusPassed:
+		return "PASS  "
+	case grader.StatusFailed:
+		return "FAIL  "
+	case grader.StatusSkipped:
+		return "SKIP  "
+	case grader.StatusCancelled:
+		return "CANCEL"
+	}
+	return strings.ToUpper(string(status))
+}
+
+func score(earned, max float64) string {
+	return fmt.Sprintf("%.2f/%.2f", earned, max)
+}
@@ -0,0 +1,79 @@
+// Command roster imports a CSV roster (student_number, name, email) into a course
+// and writes the credentials of the accounts it created as a CSV sheet.
+//
+//	go run ./cmd/roster -course 1 -in roster.csv -out credentials.csv
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/database"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	courseID := flag.Uint("course", 0, "id of the course to enroll the students in")
+	in := flag.String("in", "", "roster CSV to import (default stdin)")
+	out := flag.String("out", "", "where to write the credentials CSV (default stdout)")
+	flag.Parse()
+	if *courseID == 0 {
+		log.Fatal("-course is required")
+	}
+
+	cfg := config.LoadConfig()
+	db, err := database.OpenDatabase(cfg.DbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	// Keep the SQL log out of the credentials, which may go to stdout.
+	db.Logger = db.Logger.LogMode(logger.Silent)
+
+	roster := os.Stdin
+	if *in != "" {
+		if roster, err = os.Open(*in); err != nil {
+			log.Fatalf("Failed to open roster: %v", err)
+		}
+		defer roster.Close()
+	}
+	entries, err := course.ParseRoster(roster)
+	if err != nil {
+		log.Fatalf("Failed to read roster: %v", err)
+	}
+
+	users := user.NewUserService(*user.NewUserRepo(db), cfg)
+	svc := course.NewCourseService(*course.NewCourseRepo(db), course.NewAccess(db), users, cfg)
+	creds, err := svc.ImportRoster(*courseID, entries)
+	if err != nil {
+		log.Fatalf("Failed to import roster: %v", err)
+	}
+
+	sheet := os.Stdout
+	if *out != "" {
+		// The sheet holds passwords, so keep it private to the user running the import.
+		if sheet, err = os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err != nil {
+			log.Fatalf("Failed to create credentials file: %v", err)
+		}
+		defer sheet.Close()
+	}
+	if err := course.WriteCredentials(sheet, creds); err != nil {
+		log.Fatalf("Failed to write credentials: %v", err)
+	}
+
+	created, conflicts := 0, 0
+	for _, c := range creds {
+		if c.Created {
+			created++
+		} else if c.Conflict {
+			conflicts++
+		}
+	}
+	log.Printf("Imported %d students into course %d, %d new accounts.", len(creds)-conflicts, *courseID, created)
+	if conflicts > 0 {
+		log.Printf("Skipped %d students whose usernames belong to accounts that aren't students'; see the conflict rows.", conflicts)
+	}
+}
@@ -0,0 +1,92 @@
+// Command suite imports a project from a YAML or JSON suite file into a course, or
+// exports a project as one.
+//
+//	go run ./cmd/suite import -course 1 -in blog.yaml
+//	go run ./cmd/suite export -project 3 -format json -out blog.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/project"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/database"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	if len(os.Args) < 2 || (os.Args[1] != "import" && os.Args[1] != "export") {
+		fmt.Fprintln(os.Stderr, "usage: suite import -course <id> [-in file]")
+		fmt.Fprintln(os.Stderr, "       suite export -project <id> [-format yaml|json] [-out file]")
+		os.Exit(2)
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	courseID := flags.Uint("course", 0, "id of the course to import the project into")
+	in := flags.String("in", "", "suite to import (default stdin)")
+	projID := flags.Uint("project", 0, "id of the project to export")
+	format := flags.String("format", "yaml", "format to export in, yaml or json")
+	out := flags.String("out", "", "where to write the exported suite (default stdout)")
+	flags.Parse(os.Args[2:])
+
+	cfg := config.LoadConfig()
+	db, err := database.OpenDatabase(cfg.DbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	// Keep the SQL log out of the suite, which may go to stdout.
+	db.Logger = db.Logger.LogMode(logger.Silent)
+	repo := project.NewProjectRepo(db)
+
+	if os.Args[1] == "import" {
+		if *courseID == 0 {
+			log.Fatal("-course is required")
+		}
+		file := os.Stdin
+		if *in != "" {
+			if file, err = os.Open(*in); err != nil {
+				log.Fatalf("Failed to open suite: %v", err)
+			}
+			defer file.Close()
+		}
+		suite, err := grader.ReadSuite(file)
+		if err != nil {
+			log.Fatalf("Failed to read suite: %v", err)
+		}
+		proj, created, err := repo.ImportSuite(*courseID, suite)
+		if err != nil {
+			log.Fatalf("Failed to import suite: %v", err)
+		}
+		verb := "Updated"
+		if created {
+			verb = "Created"
+		}
+		log.Printf("%s project %d '%s' in course %d.", verb, proj.ID, proj.Name, *courseID)
+		return
+	}
+
+	if *projID == 0 {
+		log.Fatal("-project is required")
+	}
+	if *format != string(grader.SuiteYAML) && *format != string(grader.SuiteJSON) {
+		log.Fatal("-format must be yaml or json")
+	}
+	suite, err := repo.ExportSuite(*projID)
+	if err != nil {
+		log.Fatalf("Failed to export project: %v", err)
+	}
+	file := os.Stdout
+	if *out != "" {
+		if file, err = os.Create(*out); err != nil {
+			log.Fatalf("Failed to create suite file: %v", err)
+		}
+		defer file.Close()
+	}
+	if err := grader.WriteSuite(file, suite, grader.SuiteFormat(*format)); err != nil {
+		log.Fatalf("Failed to write suite: %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+package course
+
+import "errors"
+
+var ErrNotFound = errors.New("not found")
+var ErrInvalidRoster = errors.New("invalid roster")
+var ErrUserNotFound = errors.New("user not found")
+var ErrForbidden = errors.New("forbidden")
@@ -0,0 +1,50 @@
+package course
+
+import (
+	"time"
+
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/model"
+)
+
+type Course struct {
+	model.Model
+	Name string `json:"name" gorm:"type:varchar(100)"`
+}
+
+func (Course) TableName() string {
+	return "courses"
+}
+
+// MemberRole is what a user is in a course they're enrolled in. Staff run the course:
+// they edit its projects and see everyone's results, as far as their user role allows.
+type MemberRole string
+
+const (
+	MemberStudent MemberRole = "student"
+	MemberStaff   MemberRole = "staff"
+)
+
+func (r MemberRole) Valid() bool {
+	return r == MemberStudent || r == MemberStaff
+}
+
+// Enrollment puts a user in a course.
+type Enrollment struct {
+	CourseID  uint       `json:"course_id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"primaryKey"`
+	Role      MemberRole `json:"role" gorm:"type:varchar(20);default:student"`
+	CreatedAt time.Time  `json:"-"`
+}
+
+// Member is an enrolled user as listed to a course's staff.
+type Member struct {
+	UserID   uint       `json:"user_id"`
+	Username string     `json:"username"`
+	Name     string     `json:"name"`
+	Email    string     `json:"email"`
+	Role     MemberRole `json:"role"`
+}
+
+func (Enrollment) TableName() string {
+	return "enrollments"
+}
@@ -0,0 +1,130 @@
+package course
+
+import (
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CourseRepo struct {
+	db *gorm.DB
+}
+
+func NewCourseRepo(db *gorm.DB) *CourseRepo {
+	return &CourseRepo{db}
+}
+
+func (repo CourseRepo) WithDB(db *gorm.DB) *CourseRepo {
+	repo.db = db
+	return &repo
+}
+
+// Transaction runs fn with a repo bound to a transaction.
+func (repo *CourseRepo) Transaction(fn func(tx *CourseRepo) error) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		return fn(repo.WithDB(tx))
+	})
+}
+
+// List returns the courses with ids, or all courses if ids is nil.
+func (repo *CourseRepo) List(ids []uint) ([]Course, error) {
+	var courses []Course
+	query := repo.db.Order("id")
+	if ids != nil {
+		query = query.Where("id IN ?", ids)
+	}
+	result := query.Find(&courses)
+	return courses, result.Error
+}
+
+func (repo *CourseRepo) FindById(id uint) (*Course, error) {
+	var course Course
+	result := repo.db.First(&course, id)
+	return &course, result.Error
+}
+
+func (repo *CourseRepo) Create(course *Course) error {
+	return repo.db.Create(course).Error
+}
+
+// CourseIDs returns the ids of the courses a user is enrolled in with one of roles.
+func (repo *CourseRepo) CourseIDs(userID uint, roles ...MemberRole) ([]uint, error) {
+	ids := []uint{}
+	result := repo.db.Model(&Enrollment{}).
+		Where("user_id = ? AND role IN ?", userID, roles).
+		Order("course_id").
+		Pluck("course_id", &ids)
+	return ids, result.Error
+}
+
+// FindEnrollment returns a user's enrollment in a course.
+func (repo *CourseRepo) FindEnrollment(courseID, userID uint) (*Enrollment, error) {
+	var enrollment Enrollment
+	result := repo.db.Where("course_id = ? AND user_id = ?", courseID, userID).First(&enrollment)
+	return &enrollment, result.Error
+}
+
+func (repo *CourseRepo) Members(courseID uint) ([]Member, error) {
+	var members []Member
+	result := repo.db.Table("enrollments").
+		Select("users.id AS user_id, users.username, users.name, users.email, enrollments.role").
+		Joins("JOIN users ON users.id = enrollments.user_id AND users.deleted_at IS NULL").
+		Where("enrollments.course_id = ?", courseID).
+		Order("enrollments.role, users.username").
+		Scan(&members)
+	return members, result.Error
+}
+
+// SaveMember enrolls a user in a course, or changes their role if they already are.
+func (repo *CourseRepo) SaveMember(enrollment *Enrollment) error {
+	return repo.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+	}).Create(enrollment).Error
+}
+
+// RemoveMember reports false if the user wasn't enrolled in the course.
+func (repo *CourseRepo) RemoveMember(courseID, userID uint) (bool, error) {
+	result := repo.db.Where("course_id = ? AND user_id = ?", courseID, userID).Delete(&Enrollment{})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (repo *CourseRepo) FindUser(id uint) (*user.User, error) {
+	var u user.User
+	result := repo.db.First(&u, id)
+	return &u, result.Error
+}
+
+// FindUsersByUsername returns the users among usernames that already exist, by username.
+func (repo *CourseRepo) FindUsersByUsername(usernames []string) (map[string]user.User, error) {
+	var users []user.User
+	if len(usernames) > 0 {
+		if err := repo.db.Where("username IN ?", usernames).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	}
+	byUsername := make(map[string]user.User, len(users))
+	for _, u := range users {
+		byUsername[u.Username] = u
+	}
+	return byUsername, nil
+}
+
+func (repo *CourseRepo) CreateUser(u *user.User) error {
+	return repo.db.Create(u).Error
+}
+
+func (repo *CourseRepo) UpdateContact(u *user.User) error {
+	return repo.db.Model(u).Select("name", "email").Updates(u).Error
+}
+
+// Enroll adds users to a course as students; users already enrolled are left alone.
+func (repo *CourseRepo) Enroll(courseID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	rows := make([]Enrollment, len(userIDs))
+	for i, id := range userIDs {
+		rows[i] = Enrollment{CourseID: courseID, UserID: id, Role: MemberStudent}
+	}
+	return repo.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
@@ -0,0 +1,146 @@
+package course
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/mail"
+	"strings"
+
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/spreadsheet"
+)
+
+// RosterEntry is a student listed on a roster.
+type RosterEntry struct {
+	StudentNumber string
+	Name          string
+	Email         string
+}
+
+// Credential is what a student needs to log in after an import. Password is only
+// set for accounts created by the import; existing accounts keep theirs. Conflict is
+// set for a username taken by an account that isn't a student's, which the import
+// neither changed nor enrolled.
+type Credential struct {
+	RosterEntry
+	Username string
+	Password string
+	Created  bool
+	Conflict bool
+}
+
+var rosterColumns = []string{"student_number", "name", "email"}
+
+// ParseRoster reads a CSV roster whose header names the student_number, name and
+// email columns, in any order. Rows are checked before anything is imported.
+func ParseRoster(r io.Reader) ([]RosterEntry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: the roster is empty", ErrInvalidRoster)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRoster, err)
+	}
+	index := make(map[string]int, len(header))
+	for i, col := range header {
+		index[strings.ToLower(strings.TrimSpace(col))] = i
+	}
+	for _, col := range rosterColumns {
+		if _, ok := index[col]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidRoster, col)
+		}
+	}
+
+	var entries []RosterEntry
+	seen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRoster, err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		entry := RosterEntry{
+			StudentNumber: strings.TrimSpace(record[index["student_number"]]),
+			Name:          strings.TrimSpace(record[index["name"]]),
+			Email:         strings.TrimSpace(record[index["email"]]),
+		}
+		if err := entry.validate(); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidRoster, line, err)
+		}
+		if prev, ok := seen[entry.StudentNumber]; ok {
+			return nil, fmt.Errorf("%w: line %d: student number %s is already on line %d",
+				ErrInvalidRoster, line, entry.StudentNumber, prev)
+		}
+		seen[entry.StudentNumber] = line
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (e RosterEntry) validate() error {
+	switch {
+	case e.StudentNumber == "":
+		return errors.New("student number is empty")
+	case len(e.StudentNumber) > 25:
+		return fmt.Errorf("student number %s is longer than 25 characters", e.StudentNumber)
+	case len(e.Name) > 100:
+		return fmt.Errorf("name of %s is longer than 100 characters", e.StudentNumber)
+	}
+	if addr, err := mail.ParseAddress(e.Email); err != nil || addr.Address != e.Email || len(e.Email) > 255 {
+		return fmt.Errorf("email %q of %s is invalid", e.Email, e.StudentNumber)
+	}
+	return nil
+}
+
+// WriteCredentials writes credentials as a CSV sheet to hand out to students. Like the
+// gradebook, it escapes what students could make a spreadsheet read as a formula.
+func WriteCredentials(w io.Writer, creds []Credential) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"student_number", "name", "email", "username", "password", "status"})
+	for _, c := range creds {
+		status := "existing"
+		if c.Created {
+			status = "created"
+		} else if c.Conflict {
+			status = "conflict"
+		}
+		writer.Write([]string{
+			spreadsheet.Text(c.StudentNumber),
+			spreadsheet.Text(c.Name),
+			spreadsheet.Text(c.Email),
+			spreadsheet.Text(c.Username),
+			c.Password,
+			status,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// passwordAlphabet leaves out characters that are easy to misread on a printed sheet.
+const passwordAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const passwordLength = 12
+
+func generatePassword() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for range passwordLength {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		sb.WriteByte(passwordAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,169 @@
+package course
+
+import (
+	"errors"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"gorm.io/gorm"
+)
+
+type CourseService struct {
+	repo   CourseRepo
+	access *Access
+	users  *user.UserService
+	cfg    *config.AppConfig
+}
+
+func NewCourseService(repo CourseRepo, access *Access, users *user.UserService, cfg *config.AppConfig) *CourseService {
+	return &CourseService{
+		repo:   repo,
+		access: access,
+		users:  users,
+		cfg:    cfg,
+	}
+}
+
+// List returns the courses viewer is enrolled in.
+func (s *CourseService) List(viewer *user.User) ([]Course, error) {
+	ids, err := s.access.CourseIDs(viewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.List(ids)
+}
+
+// Create creates a course with its creator on the staff.
+func (s *CourseService) Create(creator *user.User, course *Course) error {
+	return s.repo.Transaction(func(tx *CourseRepo) error {
+		if err := tx.Create(course); err != nil {
+			return err
+		}
+		return tx.SaveMember(&Enrollment{CourseID: course.ID, UserID: creator.ID, Role: MemberStaff})
+	})
+}
+
+// CheckStaff returns ErrNotFound if viewer can't see the course at all, and
+// ErrForbidden if they can but aren't on its staff.
+func (s *CourseService) CheckStaff(viewer *user.User, courseID uint) error {
+	if _, err := s.repo.FindById(courseID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if ok, err := s.access.IsStaff(viewer, courseID); err != nil || ok {
+		return err
+	}
+	ok, err := s.access.IsMember(viewer, courseID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return ErrForbidden
+}
+
+func (s *CourseService) Members(viewer *user.User, courseID uint) ([]Member, error) {
+	if err := s.CheckStaff(viewer, courseID); err != nil {
+		return nil, err
+	}
+	return s.repo.Members(courseID)
+}
+
+// SaveMember enrolls a user in the course as role, or changes the role they have in it.
+func (s *CourseService) SaveMember(viewer *user.User, courseID, userID uint, role MemberRole) (*Enrollment, error) {
+	if err := s.CheckStaff(viewer, courseID); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.FindUser(userID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	enrollment := &Enrollment{CourseID: courseID, UserID: userID, Role: role}
+	return enrollment, s.repo.SaveMember(enrollment)
+}
+
+func (s *CourseService) RemoveMember(viewer *user.User, courseID, userID uint) error {
+	if err := s.CheckStaff(viewer, courseID); err != nil {
+		return err
+	}
+	ok, err := s.repo.RemoveMember(courseID, userID)
+	if err == nil && !ok {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// ImportRoster makes sure every student on the roster has an account and is enrolled
+// in the course. Importing the same roster again changes nothing but names and
+// emails: existing accounts keep their password and role, and only accounts created
+// by this import get a password in the returned credentials. An existing account that
+// isn't a student's, e.g. staff whose username is a student number, is left alone
+// and reported as a conflict.
+func (s *CourseService) ImportRoster(courseID uint, entries []RosterEntry) ([]Credential, error) {
+	if _, err := s.repo.FindById(courseID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	creds := make([]Credential, len(entries))
+	err := s.repo.Transaction(func(tx *CourseRepo) error {
+		usernames := make([]string, len(entries))
+		for i, e := range entries {
+			usernames[i] = e.StudentNumber
+		}
+		existing, err := tx.FindUsersByUsername(usernames)
+		if err != nil {
+			return err
+		}
+
+		userIDs := make([]uint, 0, len(entries))
+		for i, e := range entries {
+			creds[i] = Credential{RosterEntry: e, Username: e.StudentNumber}
+			if u, ok := existing[e.StudentNumber]; ok {
+				if u.Role != user.RoleStudent {
+					creds[i].Conflict = true
+					continue
+				}
+				u.Name, u.Email = e.Name, e.Email
+				if err := tx.UpdateContact(&u); err != nil {
+					return err
+				}
+				userIDs = append(userIDs, u.ID)
+				continue
+			}
+
+			password, err := generatePassword()
+			if err != nil {
+				return err
+			}
+			hash, err := s.users.HashPassword(password)
+			if err != nil {
+				return err
+			}
+			u := user.User{
+				Username: e.StudentNumber,
+				Name:     e.Name,
+				Email:    e.Email,
+				Password: hash,
+				Role:     user.RoleStudent,
+			}
+			if err := tx.CreateUser(&u); err != nil {
+				return err
+			}
+			creds[i].Password = password
+			creds[i].Created = true
+			userIDs = append(userIDs, u.ID)
+		}
+		return tx.Enroll(courseID, userIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
@@ -0,0 +1,14 @@
+package grading
+
+import "errors"
+
+var ErrProjectNotFound = errors.New("project not found")
+var ErrNotFound = errors.New("not found")
+var ErrNotCancellable = errors.New("grading already finished")
+
+// ErrNoSubmission is returned when grading is requested before a base URL was submitted.
+var ErrNoSubmission = errors.New("no active submission")
+
+// ErrAlreadyQueued is returned when grading is requested while an earlier one of the
+// same user or team is still queued or running.
+var ErrAlreadyQueued = errors.New("grading already queued")
@@ -0,0 +1,100 @@
+package grading
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/spreadsheet"
+)
+
+// GradebookRow is a student's grade for a project. A team's grade is repeated for
+// each of its members.
+type GradebookRow struct {
+	UserID   uint                 `json:"user_id"`
+	Username string               `json:"username"`
+	Name     string               `json:"name"`
+	Email    string               `json:"email"`
+	TeamID   *uint                `json:"team_id"`
+	TeamName string               `json:"team_name"`
+	ResultID *uint                `json:"result_id"`
+	Status   grader.GradingStatus `json:"status"`
+	Score    float64              `json:"score"`
+	MaxScore float64              `json:"max_score"`
+}
+
+// Gradebook grades every student of the project's course by their latest completed
+// result: their team's if they're in one, their own otherwise. Students without one
+// are listed without a result.
+func (s *GradingService) Gradebook(viewer *user.User, projID uint) (*grader.Project, []GradebookRow, error) {
+	proj, err := s.staffProject(viewer, projID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := s.repo.FindGradebookStudents(proj.CourseID, proj.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	results, err := s.repo.FindCompletedResults(proj.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// results are newest first, so the first one seen for each owner is the latest.
+	byUser := make(map[uint]*grader.ProjectResult)
+	byTeam := make(map[uint]*grader.ProjectResult)
+	for i := range results {
+		r := &results[i]
+		if r.TeamID != nil {
+			if _, ok := byTeam[*r.TeamID]; !ok {
+				byTeam[*r.TeamID] = r
+			}
+		} else if _, ok := byUser[r.UserID]; !ok {
+			byUser[r.UserID] = r
+		}
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		result := byUser[row.UserID]
+		if row.TeamID != nil {
+			result = byTeam[*row.TeamID]
+		}
+		if result == nil {
+			continue
+		}
+		row.ResultID = &result.ID
+		row.Status = result.Status
+		row.Score = result.Score
+		row.MaxScore = result.MaxScore
+	}
+	return proj, rows, nil
+}
+
+// WriteGradebook writes the gradebook as CSV. Names and the like are escaped so that
+// spreadsheets opening the file don't take them for formulas.
+func WriteGradebook(w io.Writer, rows []GradebookRow) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"username", "name", "email", "team", "result_id", "status", "score", "max_score"})
+	for _, row := range rows {
+		resultID := ""
+		if row.ResultID != nil {
+			resultID = strconv.FormatUint(uint64(*row.ResultID), 10)
+		}
+		writer.Write([]string{
+			spreadsheet.Text(row.Username),
+			spreadsheet.Text(row.Name),
+			spreadsheet.Text(row.Email),
+			spreadsheet.Text(row.TeamName),
+			resultID,
+			string(row.Status),
+			strconv.FormatFloat(row.Score, 'f', -1, 64),
+			strconv.FormatFloat(row.MaxScore, 'f', -1, 64),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
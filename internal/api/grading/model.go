@@ -0,0 +1,46 @@
+package grading
+
+import (
+	"time"
+
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/model"
+)
+
+// maxJobAttempts is how many times a job is started before recovery gives up on it.
+const maxJobAttempts = 3
+
+// maxOutput is how much of what an uploaded submission prints is kept in its result.
+const maxOutput = 64 << 10
+
+// JobStatus defines the state of a grading job in the queue.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Job is a persisted request to grade the submission that was active when the job was
+// queued. BaseUrl is copied from the submission, and is empty for uploaded ones.
+// Its progress is reported through the linked grader.ProjectResult.
+type Job struct {
+	model.Model
+	ProjectID       uint       `json:"project_id"`
+	UserID          uint       `json:"user_id"`
+	TeamID          *uint      `json:"team_id,omitempty"`
+	SubmissionID    *uint      `json:"submission_id"`
+	BaseUrl         string     `json:"base_url"`
+	ProjectResultID uint       `json:"project_result_id"`
+	Status          JobStatus  `json:"status"`
+	Attempts        uint       `json:"attempts"`
+	Error           string     `json:"error,omitempty"`
+	StartedAt       *time.Time `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at"`
+}
+
+func (Job) TableName() string {
+	return "grade_jobs"
+}
@@ -0,0 +1,281 @@
+package grading
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/submission"
+	"github.com/sinasadeghi83/aut-grader/internal/api/team"
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepo struct {
+	db *gorm.DB
+}
+
+func NewJobRepo(db *gorm.DB) *JobRepo {
+	return &JobRepo{db}
+}
+
+func (repo JobRepo) WithDB(db *gorm.DB) *JobRepo {
+	repo.db = db
+	return &repo
+}
+
+// Create stores a queued job together with the result it will report to, unless the
+// job's user or team already has one queued or running for the project, which it
+// reports with false. The project's row stays locked meanwhile, so that requests made
+// at once can't both get through.
+func (repo *JobRepo) Create(job *Job, result *grader.ProjectResult) (bool, error) {
+	created := false
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var proj grader.Project
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&proj, job.ProjectID).Error; err != nil {
+			return err
+		}
+
+		var pending int64
+		query := tx.Model(&Job{}).Where("project_id = ? AND status IN ?", job.ProjectID, []JobStatus{JobQueued, JobRunning})
+		if job.TeamID != nil {
+			query = query.Where("user_id = ? OR team_id = ?", job.UserID, *job.TeamID)
+		} else {
+			query = query.Where("user_id = ?", job.UserID)
+		}
+		if err := query.Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return nil
+		}
+
+		if err := tx.Create(result).Error; err != nil {
+			return err
+		}
+		job.ProjectResultID = result.ID
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// ClaimNext marks the oldest queued job as running and returns it,
+// or returns nil when the queue is empty.
+func (repo *JobRepo) ClaimNext() (*Job, error) {
+	for {
+		var job Job
+		result := repo.db.Where("status = ?", JobQueued).Order("id").Limit(1).Find(&job)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, nil
+		}
+
+		now := time.Now()
+		claim := repo.db.Model(&Job{}).
+			Where("id = ? AND status = ?", job.ID, JobQueued).
+			Updates(map[string]interface{}{
+				"status":     JobRunning,
+				"started_at": now,
+				"attempts":   gorm.Expr("attempts + 1"),
+			})
+		if claim.Error != nil {
+			return nil, claim.Error
+		}
+		if claim.RowsAffected == 1 {
+			job.Status = JobRunning
+			job.StartedAt = &now
+			job.Attempts++
+			return &job, nil
+		}
+		// Another worker claimed it first, try the next one.
+	}
+}
+
+func (repo *JobRepo) FindByResult(resultID uint) (*Job, error) {
+	var job Job
+	result := repo.db.Where("project_result_id = ?", resultID).First(&job)
+	return &job, result.Error
+}
+
+// CancelQueued cancels a job that hasn't been claimed yet, along with its result.
+// It reports false if a worker claimed the job first.
+func (repo *JobRepo) CancelQueued(job *Job, reason string) (bool, error) {
+	cancelled := false
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		update := tx.Model(&Job{}).
+			Where("id = ? AND status = ?", job.ID, JobQueued).
+			Updates(map[string]interface{}{"status": JobCancelled, "finished_at": now})
+		if update.Error != nil || update.RowsAffected == 0 {
+			return update.Error
+		}
+
+		cancelled = true
+		job.Status = JobCancelled
+		job.FinishedAt = &now
+		return tx.Model(&grader.ProjectResult{}).
+			Where("id = ?", job.ProjectResultID).
+			Updates(map[string]interface{}{"status": grader.StatusCancelled, "message": reason}).Error
+	})
+	return cancelled, err
+}
+
+func (repo *JobRepo) FindRunning() ([]Job, error) {
+	var jobs []Job
+	result := repo.db.Where("status = ?", JobRunning).Order("id").Find(&jobs)
+	return jobs, result.Error
+}
+
+// Requeue puts a job that was cut short back in the queue with a fresh result,
+// pointing its interrupted result at the new one.
+func (repo *JobRepo) Requeue(job *Job, reason string) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		var old grader.ProjectResult
+		if err := tx.First(&old, job.ProjectResultID).Error; err != nil {
+			return err
+		}
+
+		result := &grader.ProjectResult{
+			ProjectID:   old.ProjectID,
+			ProjectName: old.ProjectName,
+			UserID:      old.UserID,
+			TeamID:      old.TeamID,
+			Status:      grader.StatusQueued,
+			Message:     "Queued...",
+		}
+		if err := tx.Create(result).Error; err != nil {
+			return err
+		}
+
+		old.Status = grader.StatusInterrupted
+		old.Message = fmt.Sprintf("%s Re-queued as result #%d.", reason, result.ID)
+		if err := tx.Save(&old).Error; err != nil {
+			return err
+		}
+
+		job.ProjectResultID = result.ID
+		job.Status = JobQueued
+		job.StartedAt = nil
+		return tx.Model(job).Select("project_result_id", "status", "started_at").Updates(job).Error
+	})
+}
+
+// Abandon fails a job that was cut short too many times, together with its result.
+func (repo *JobRepo) Abandon(job *Job, reason string) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&grader.ProjectResult{}).
+			Where("id = ?", job.ProjectResultID).
+			Updates(map[string]interface{}{"status": grader.StatusInterrupted, "message": reason}).Error
+		if err != nil {
+			return err
+		}
+		return repo.WithDB(tx).Finish(job, JobFailed, reason)
+	})
+}
+
+func (repo *JobRepo) Finish(job *Job, status JobStatus, errMsg string) error {
+	now := time.Now()
+	job.Status = status
+	job.Error = errMsg
+	job.FinishedAt = &now
+	return repo.db.Model(job).Select("status", "error", "finished_at").Updates(job).Error
+}
+
+func (repo *JobRepo) FindProject(id uint) (*grader.Project, error) {
+	var proj grader.Project
+	result := repo.db.First(&proj, id)
+	return &proj, result.Error
+}
+
+func (repo *JobRepo) FindResult(id uint) (*grader.ProjectResult, error) {
+	var result grader.ProjectResult
+	err := repo.db.First(&result, id).Error
+	return &result, err
+}
+
+func (repo *JobRepo) FindResultTree(id uint) (*grader.ProjectResult, error) {
+	var result grader.ProjectResult
+	err := repo.db.Preload("Sections.Scenarios.Tests.Assertions").First(&result, id).Error
+	return &result, err
+}
+
+// FindActiveSubmission returns the submission to grade for the user, or for their team.
+func (repo *JobRepo) FindActiveSubmission(projID, userID uint, teamID *uint) (*submission.Submission, error) {
+	return submission.NewSubmissionRepo(repo.db).FindActive(projID, userID, teamID)
+}
+
+func (repo *JobRepo) FindSubmission(id uint) (*submission.Submission, error) {
+	return submission.NewSubmissionRepo(repo.db).FindById(id)
+}
+
+// EndResult records how a grading ended that never got to run its tests.
+func (repo *JobRepo) EndResult(result *grader.ProjectResult, status grader.GradingStatus, message, output string) error {
+	result.Status = status
+	result.Message = message
+	result.Output = output
+	return repo.db.Model(result).Select("status", "message", "output").Updates(result).Error
+}
+
+func (repo *JobRepo) SaveOutput(resultID uint, output string) error {
+	return repo.db.Model(&grader.ProjectResult{}).Where("id = ?", resultID).Update("output", output).Error
+}
+
+// FindTeamID returns the team the user is in for the project, or nil if there is none.
+func (repo *JobRepo) FindTeamID(projID, userID uint) (*uint, error) {
+	return team.NewTeamRepo(repo.db).FindTeamID(projID, userID)
+}
+
+func (repo *JobRepo) IsTeamMember(teamID, userID uint) (bool, error) {
+	return team.NewTeamRepo(repo.db).IsMember(teamID, userID)
+}
+
+// resultOwner picks the results of a user or of their team.
+type resultOwner struct {
+	UserID uint
+	TeamID *uint
+}
+
+// ListResults returns the results of a project, newest first, optionally only those of owner.
+func (repo *JobRepo) ListResults(projID uint, owner *resultOwner) ([]grader.ProjectResult, error) {
+	results := []grader.ProjectResult{}
+	query := repo.db.Where("project_id = ?", projID).Order("id DESC")
+	if owner != nil {
+		if owner.TeamID != nil {
+			query = query.Where("user_id = ? OR team_id = ?", owner.UserID, *owner.TeamID)
+		} else {
+			query = query.Where("user_id = ?", owner.UserID)
+		}
+	}
+	err := query.Find(&results).Error
+	return results, err
+}
+
+// FindGradebookStudents lists the students of a course with their team for the project.
+func (repo *JobRepo) FindGradebookStudents(courseID, projID uint) ([]GradebookRow, error) {
+	rows := []GradebookRow{}
+	err := repo.db.Table("enrollments").
+		Select("users.id AS user_id, users.username, users.name, users.email, teams.id AS team_id, COALESCE(teams.name, '') AS team_name").
+		Joins("JOIN users ON users.id = enrollments.user_id AND users.deleted_at IS NULL").
+		Joins("LEFT JOIN team_members ON team_members.user_id = users.id AND team_members.project_id = ?", projID).
+		Joins("LEFT JOIN teams ON teams.id = team_members.team_id AND teams.deleted_at IS NULL").
+		Where("enrollments.course_id = ? AND enrollments.role = ?", courseID, course.MemberStudent).
+		Order("users.username").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// FindCompletedResults returns the results of a project whose grading ran to the end, newest first.
+func (repo *JobRepo) FindCompletedResults(projID uint) ([]grader.ProjectResult, error) {
+	var results []grader.ProjectResult
+	err := repo.db.Where("project_id = ? AND status IN ?", projID, []grader.GradingStatus{grader.StatusPassed, grader.StatusFailed}).
+		Order("id DESC").
+		Find(&results).Error
+	return results, err
+}
@@ -0,0 +1,396 @@
+package grading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/submission"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"github.com/sinasadeghi83/aut-grader/pkg/runner"
+	"gorm.io/gorm"
+)
+
+type GradingService struct {
+	repo   JobRepo
+	access *course.Access
+	cfg    *config.AppConfig
+	notify chan struct{}
+
+	// mu guards the cancellation of jobs claimed by this process. Their cancel funcs are
+	// nil until they start running; cancelled marks the ones cancelled before that.
+	mu        sync.Mutex
+	cancels   map[uint]context.CancelFunc
+	cancelled map[uint]bool
+}
+
+func NewGradingService(repo JobRepo, access *course.Access, cfg *config.AppConfig) *GradingService {
+	return &GradingService{
+		repo:      repo,
+		access:    access,
+		cfg:       cfg,
+		notify:    make(chan struct{}, 1),
+		cancels:   make(map[uint]context.CancelFunc),
+		cancelled: make(map[uint]bool),
+	}
+}
+
+// Enqueue persists a grading job for the active submission of the user, or of their
+// team, and wakes up an idle worker. Users can only grade projects of the courses
+// they're enrolled in. The grading counts for the user's team if they're in one, and
+// only one grading of the user or team may be queued or running at a time.
+func (s *GradingService) Enqueue(viewer *user.User, projID uint) (*Job, error) {
+	proj, err := s.repo.FindProject(projID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.access.IsMember(viewer, proj.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrProjectNotFound
+	}
+	teamID, err := s.repo.FindTeamID(proj.ID, viewer.ID)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := s.repo.FindActiveSubmission(proj.ID, viewer.ID, teamID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoSubmission
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{
+		ProjectID:    proj.ID,
+		UserID:       viewer.ID,
+		TeamID:       teamID,
+		SubmissionID: &sub.ID,
+		BaseUrl:      sub.BaseUrl,
+		Status:       JobQueued,
+	}
+	result := &grader.ProjectResult{
+		ProjectID:   proj.ID,
+		ProjectName: proj.Name,
+		UserID:      viewer.ID,
+		TeamID:      teamID,
+		Status:      grader.StatusQueued,
+		Message:     "Queued...",
+	}
+	created, err := s.repo.Create(job, result)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrAlreadyQueued
+	}
+
+	s.wake()
+	return job, nil
+}
+
+// FindResult returns a project result with its whole tree, as long as viewer may see it.
+func (s *GradingService) FindResult(id uint, viewer *user.User) (*grader.ProjectResult, error) {
+	result, err := s.repo.FindResultTree(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAccess(viewer, result.UserID, result.TeamID, result.ProjectID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListResults returns the results of a project without their trees: all of them to
+// its course's staff, otherwise the viewer's own and their team's.
+func (s *GradingService) ListResults(viewer *user.User, projID uint) ([]grader.ProjectResult, error) {
+	_, err := s.staffProject(viewer, projID)
+	if err == nil {
+		return s.repo.ListResults(projID, nil)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	proj, err := s.repo.FindProject(projID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.access.IsMember(viewer, proj.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrProjectNotFound
+	}
+	teamID, err := s.repo.FindTeamID(projID, viewer.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListResults(projID, &resultOwner{UserID: viewer.ID, TeamID: teamID})
+}
+
+// Cancel stops the grading behind a result viewer may access. A queued job never starts;
+// a running one stops shortly and whatever it didn't get to is marked as cancelled.
+func (s *GradingService) Cancel(resultID uint, viewer *user.User) error {
+	job, err := s.repo.FindByResult(resultID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.checkAccess(viewer, job.UserID, job.TeamID, job.ProjectID); err != nil {
+		return err
+	}
+
+	if job.Status == JobQueued {
+		ok, err := s.repo.CancelQueued(job, "Cancelled: grading was cancelled before it started.")
+		if err != nil || ok {
+			return err
+		}
+		// A worker claimed it in the meantime.
+		job.Status = JobRunning
+	}
+	if job.Status != JobRunning {
+		return ErrNotCancellable
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cancel, ok := s.cancels[job.ID]
+	switch {
+	case !ok:
+		// Jobs are claimed under the lock, so it has finished since it was loaded.
+		return ErrNotCancellable
+	case cancel == nil:
+		// Claimed but not started yet, track picks this up.
+		s.cancelled[job.ID] = true
+	default:
+		cancel()
+	}
+	return nil
+}
+
+// claim claims the next queued job for this process, so that Cancel can find it
+// before it starts running. It returns nil when the queue is empty.
+func (s *GradingService) claim() (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, err := s.repo.ClaimNext()
+	if job != nil {
+		s.cancels[job.ID] = nil
+	}
+	return job, err
+}
+
+// track registers the cancel func of a job starting to run, cancelling it right
+// away if that was asked for in between claiming and starting it.
+func (s *GradingService) track(jobID uint, cancel context.CancelFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancels[jobID] = cancel
+	if s.cancelled[jobID] {
+		delete(s.cancelled, jobID)
+		cancel()
+	}
+}
+
+func (s *GradingService) untrack(jobID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cancels, jobID)
+	delete(s.cancelled, jobID)
+}
+
+// Recover deals with gradings cut short by a previous run of the server: running jobs are
+// re-queued (up to maxJobAttempts) and results left processing are marked as interrupted.
+// It must run before the workers start.
+//
+// Jobs don't record which server runs them, so this takes every running job as left
+// over: only one server may grade against a database. Cancel relies on that too.
+func (s *GradingService) Recover() error {
+	const reason = "Interrupted: the server stopped before grading finished."
+
+	jobs, err := s.repo.FindRunning()
+	if err != nil {
+		return fmt.Errorf("failed to load running jobs: %w", err)
+	}
+	for i := range jobs {
+		job := &jobs[i]
+		if job.Attempts >= maxJobAttempts {
+			msg := fmt.Sprintf("%s Gave up after %d attempts.", reason, job.Attempts)
+			err = s.repo.Abandon(job, msg)
+		} else {
+			err = s.repo.Requeue(job, reason)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to recover job %d: %w", job.ID, err)
+		}
+	}
+
+	recovered, err := grader.RecoverInterrupted(s.repo.db, reason)
+	if err != nil {
+		return err
+	}
+	if len(jobs) > 0 || recovered > 0 {
+		log.Printf("Recovered %d interrupted grading jobs and %d results", len(jobs), recovered)
+	}
+	return nil
+}
+
+// run grades a claimed job and records how it ended.
+func (s *GradingService) run(ctx context.Context, job *Job) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.track(job.ID, cancel)
+	defer s.untrack(job.ID)
+
+	status, errMsg := JobDone, ""
+
+	result, err := s.repo.FindResult(job.ProjectResultID)
+	if err == nil {
+		err = s.grade(ctx, job, result)
+	}
+	if err != nil {
+		log.Printf("Grading job %d failed: %v", job.ID, err)
+		status, errMsg = JobFailed, err.Error()
+	} else if ctx.Err() != nil {
+		status, errMsg = JobCancelled, "cancelled while running"
+	}
+
+	if err := s.repo.Finish(job, status, errMsg); err != nil {
+		log.Printf("Failed to finish grading job %d: %v", job.ID, err)
+	}
+}
+
+// grade runs the grader against the job's submission. Uploaded submissions are started
+// for the grading and stopped afterwards, keeping what they printed in the result.
+func (s *GradingService) grade(ctx context.Context, job *Job, result *grader.ProjectResult) error {
+	policy := grader.NewTargetPolicy(
+		s.cfg.GraderAllowedTargets.Hosts, s.cfg.GraderBlockedTargets.Hosts,
+		s.cfg.GraderAllowedTargets.Nets, s.cfg.GraderBlockedTargets.Nets)
+	baseUrl := job.BaseUrl
+
+	var sub *submission.Submission
+	if job.SubmissionID != nil {
+		var err error
+		if sub, err = s.repo.FindSubmission(*job.SubmissionID); err != nil {
+			return fmt.Errorf("failed to load submission: %w", err)
+		}
+	}
+	if sub != nil && sub.Local() {
+		proc, err := runner.Start(ctx, filepath.Join(s.cfg.SubmissionDir, sub.Artifact), s.runnerConfig())
+		var startErr *runner.StartError
+		if errors.As(err, &startErr) {
+			if ctx.Err() != nil {
+				return s.repo.EndResult(result, grader.StatusCancelled, "Cancelled: grading was cancelled.", startErr.Output)
+			}
+			msg := fmt.Sprintf("Submission failed to start: %s", startErr.Err)
+			return s.repo.EndResult(result, grader.StatusFailed, msg, startErr.Output)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to start submission: %w", err)
+		}
+		defer func() {
+			proc.Stop()
+			if err := s.repo.SaveOutput(result.ID, proc.Output()); err != nil {
+				log.Printf("Failed to save the output of result %d: %v", result.ID, err)
+			}
+		}()
+
+		// Requests go into the submission's network namespace, and only to its own port:
+		// redirects elsewhere stay blocked.
+		baseUrl = proc.BaseUrl()
+		policy.AllowedAddrs = append(policy.AllowedAddrs, proc.Addr())
+		policy.Dial = proc.Dial
+	}
+
+	gd := grader.NewGrader(baseUrl, job.UserID)
+	gd.Concurrency = s.cfg.GraderConcurrency
+	gd.TestTimeout = s.cfg.GraderTestTimeout
+	gd.RunTimeout = s.cfg.GraderRunTimeout
+	gd.TargetPolicy = policy
+	_, err := gd.GradeProjectResult(ctx, s.repo.db, result)
+	return err
+}
+
+func (s *GradingService) runnerConfig() runner.Config {
+	return runner.Config{
+		Command:      s.cfg.RunnerCommand,
+		HealthPath:   s.cfg.RunnerHealthPath,
+		StartTimeout: s.cfg.RunnerStartTimeout,
+		WallTime:     s.cfg.RunnerWallTime,
+		CPUTime:      s.cfg.RunnerCPUTime,
+		Memory:       s.cfg.RunnerMemory,
+		MaxExtract:   4 * s.cfg.SubmissionMaxSize,
+		MaxOutput:    maxOutput,
+		ScratchDir:   s.cfg.RunnerScratchDir,
+		UID:          s.cfg.RunnerUID,
+		GID:          s.cfg.RunnerGID,
+	}
+}
+
+// wake signals the worker pool that a job may be waiting, without blocking.
+func (s *GradingService) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// checkAccess returns ErrNotFound unless viewer may see or cancel a result of ownerID
+// for the project: their own results, their team's, or anyone's in a course they're staff of.
+func (s *GradingService) checkAccess(viewer *user.User, ownerID uint, teamID *uint, projID uint) error {
+	if viewer.ID == ownerID {
+		return nil
+	}
+	if teamID != nil {
+		ok, err := s.repo.IsTeamMember(*teamID, viewer.ID)
+		if err != nil || ok {
+			return err
+		}
+	}
+	_, err := s.staffProject(viewer, projID)
+	return err
+}
+
+// staffProject returns the project if viewer may see all of its results, and
+// ErrNotFound otherwise.
+func (s *GradingService) staffProject(viewer *user.User, projID uint) (*grader.Project, error) {
+	if !viewer.Can(user.PermAllResults) {
+		return nil, ErrNotFound
+	}
+	proj, err := s.repo.FindProject(projID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.access.IsStaff(viewer, proj.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return proj, nil
+}
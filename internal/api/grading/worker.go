@@ -0,0 +1,62 @@
+package grading
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// pollInterval bounds how long a queued job can wait when no wake-up signal reaches the workers,
+// e.g. for jobs left in the queue by a previous run of the server.
+const pollInterval = 5 * time.Second
+
+// WorkerPool runs queued grading jobs on a fixed number of goroutines.
+type WorkerPool struct {
+	svc  *GradingService
+	size int
+}
+
+func NewWorkerPool(svc *GradingService, size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &WorkerPool{
+		svc:  svc,
+		size: size,
+	}
+}
+
+// Recover cleans up after gradings that were cut short by a previous run of the server.
+// Call it before Start.
+func (p *WorkerPool) Recover() error {
+	return p.svc.Recover()
+}
+
+// Start launches the workers. They stop once ctx is done.
+func (p *WorkerPool) Start(ctx context.Context) {
+	log.Printf("Starting %d grading workers", p.size)
+	for i := 0; i < p.size; i++ {
+		go p.work(ctx)
+	}
+}
+
+func (p *WorkerPool) work(ctx context.Context) {
+	for {
+		job, err := p.svc.claim()
+		if err != nil {
+			log.Printf("Failed to claim grading job: %v", err)
+		} else if job != nil {
+			// There may be more jobs waiting, let another idle worker look.
+			p.svc.wake()
+			p.svc.run(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.svc.notify:
+		case <-time.After(pollInterval):
+		}
+	}
+}
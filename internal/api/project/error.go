@@ -0,0 +1,48 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"gorm.io/gorm"
+)
+
+var ErrNotFound = errors.New("not found")
+var ErrForbidden = errors.New("forbidden")
+
+// ErrInvalid is returned for writes the database or the grader rejects,
+// e.g. a dependency on a section of another project, a dependency cycle or an
+// assertion that doesn't parse.
+var ErrInvalid = errors.New("invalid")
+
+// MySQL error numbers that mean the input broke a constraint rather than the server failing.
+const (
+	mysqlErrSignal          = 1644 // SIGNAL raised by the dependency triggers
+	mysqlErrDuplicateEntry  = 1062
+	mysqlErrDataTooLong     = 1406
+	mysqlErrNoReferencedRow = 1452
+)
+
+// translateError maps storage errors to the package's errors.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if errors.Is(err, grader.ErrDependencyCycle) || errors.Is(err, grader.ErrUnknownDependency) || errors.Is(err, grader.ErrInvalidSuite) || errors.Is(err, grader.ErrInvalidAssertion) || errors.Is(err, grader.ErrInvalidComparison) {
+		return fmt.Errorf("%w: %s", ErrInvalid, err.Error())
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlErrSignal, mysqlErrDuplicateEntry, mysqlErrDataTooLong, mysqlErrNoReferencedRow:
+			return fmt.Errorf("%w: %s", ErrInvalid, mysqlErr.Message)
+		}
+	}
+	return err
+}
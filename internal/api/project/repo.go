@@ -0,0 +1,222 @@
+package project
+
+import (
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProjectRepo struct {
+	db *gorm.DB
+}
+
+func NewProjectRepo(db *gorm.DB) *ProjectRepo {
+	return &ProjectRepo{db}
+}
+
+func (repo ProjectRepo) WithDB(db *gorm.DB) *ProjectRepo {
+	repo.db = db
+	return &repo
+}
+
+// Transaction runs fn with a repo bound to a transaction.
+func (repo *ProjectRepo) Transaction(fn func(tx *ProjectRepo) error) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		return fn(repo.WithDB(tx))
+	})
+}
+
+// List returns the projects of the courses with courseIDs, or all projects if courseIDs is nil.
+func (repo *ProjectRepo) List(courseIDs []uint) ([]grader.Project, error) {
+	var projects []grader.Project
+	query := repo.db.Order("id")
+	if courseIDs != nil {
+		query = query.Where("course_id IN ?", courseIDs)
+	}
+	result := query.Find(&projects)
+	return projects, result.Error
+}
+
+func (repo *ProjectRepo) FindById(id uint) (*grader.Project, error) {
+	var proj grader.Project
+	result := repo.db.First(&proj, id)
+	return &proj, result.Error
+}
+
+func (repo *ProjectRepo) FindTree(id uint) (*grader.Project, error) {
+	if _, err := repo.FindById(id); err != nil {
+		return nil, err
+	}
+	return grader.LoadProject(repo.db, id)
+}
+
+// Validate checks that the project's dependencies can still be graded.
+func (repo *ProjectRepo) Validate(projID uint) error {
+	return grader.ValidateProject(repo.db, projID)
+}
+
+func (repo *ProjectRepo) FindSection(projID, secID uint) (*grader.Section, error) {
+	var sec grader.Section
+	result := repo.db.Where("id = ? AND project_id = ?", secID, projID).First(&sec)
+	return &sec, result.Error
+}
+
+func (repo *ProjectRepo) FindScenario(projID, secID, scnID uint) (*grader.Scenario, error) {
+	var scn grader.Scenario
+	result := repo.db.
+		Joins("JOIN sections ON sections.id = scenarios.section_id AND sections.deleted_at IS NULL").
+		Where("scenarios.id = ? AND scenarios.section_id = ? AND sections.project_id = ?", scnID, secID, projID).
+		First(&scn)
+	return &scn, result.Error
+}
+
+func (repo *ProjectRepo) FindTest(projID, secID, scnID, testID uint) (*grader.Test, error) {
+	var test grader.Test
+	result := repo.db.
+		Joins("JOIN scenarios ON scenarios.id = tests.scenario_id AND scenarios.deleted_at IS NULL").
+		Joins("JOIN sections ON sections.id = scenarios.section_id AND sections.deleted_at IS NULL").
+		Where("tests.id = ? AND tests.scenario_id = ? AND scenarios.section_id = ? AND sections.project_id = ?", testID, scnID, secID, projID).
+		First(&test)
+	return &test, result.Error
+}
+
+// Save creates or updates a row of the tree, leaving its associations alone.
+func (repo *ProjectRepo) Save(value interface{}) error {
+	return repo.db.Omit(clause.Associations).Save(value).Error
+}
+
+func (repo *ProjectRepo) DeleteProject(proj *grader.Project) error {
+	return repo.db.Delete(proj).Error
+}
+
+// DeleteSection removes a section and any dependency on it.
+func (repo *ProjectRepo) DeleteSection(sec *grader.Section) error {
+	err := repo.db.Where("section_id = ? OR depends_on_id = ?", sec.ID, sec.ID).Delete(&grader.SectionDependency{}).Error
+	if err != nil {
+		return err
+	}
+	return repo.db.Delete(sec).Error
+}
+
+// DeleteScenario removes a scenario and any dependency on it.
+func (repo *ProjectRepo) DeleteScenario(scn *grader.Scenario) error {
+	err := repo.db.Where("scenario_id = ? OR depends_on_id = ?", scn.ID, scn.ID).Delete(&grader.ScenarioDependency{}).Error
+	if err != nil {
+		return err
+	}
+	return repo.db.Delete(scn).Error
+}
+
+// DeleteTest removes a test and any dependency on it.
+func (repo *ProjectRepo) DeleteTest(test *grader.Test) error {
+	err := repo.db.Where("test_id = ? OR depends_on_id = ?", test.ID, test.ID).Delete(&grader.TestDependency{}).Error
+	if err != nil {
+		return err
+	}
+	return repo.db.Delete(test).Error
+}
+
+func (repo *ProjectRepo) ReplaceSectionDependencies(secID uint, dependsOnIDs []uint) error {
+	return replaceRows(repo.db, "section_id", secID, dependsOnIDs, func(depID uint) grader.SectionDependency {
+		return grader.SectionDependency{SectionID: secID, DependsOnID: depID}
+	})
+}
+
+func (repo *ProjectRepo) ReplaceScenarioDependencies(scnID uint, dependsOnIDs []uint) error {
+	return replaceRows(repo.db, "scenario_id", scnID, dependsOnIDs, func(depID uint) grader.ScenarioDependency {
+		return grader.ScenarioDependency{ScenarioID: scnID, DependsOnID: depID}
+	})
+}
+
+func (repo *ProjectRepo) ReplaceTestDependencies(testID uint, dependsOnIDs []uint) error {
+	return replaceRows(repo.db, "test_id", testID, dependsOnIDs, func(depID uint) grader.TestDependency {
+		return grader.TestDependency{TestID: testID, DependsOnID: depID}
+	})
+}
+
+func (repo *ProjectRepo) ReplaceRequestHeaders(testID uint, headers []grader.THeader) error {
+	return replaceRows(repo.db, "test_id", testID, headers, func(h grader.THeader) grader.THeader {
+		return grader.THeader{Key: h.Key, Value: h.Value, TestID: testID}
+	})
+}
+
+func (repo *ProjectRepo) ReplaceResponseHeaders(testID uint, headers []grader.TResHeader) error {
+	return replaceRows(repo.db, "test_id", testID, headers, func(h grader.TResHeader) grader.TResHeader {
+		return grader.TResHeader{Key: h.Key, Value: h.Value, TestID: testID}
+	})
+}
+
+func (repo *ProjectRepo) ReplaceAssertions(testID uint, assertions []grader.TAssertion) error {
+	return replaceRows(repo.db, "test_id", testID, assertions, func(a grader.TAssertion) grader.TAssertion {
+		return grader.TAssertion{Expr: a.Expr, TestID: testID}
+	})
+}
+
+func (repo *ProjectRepo) ReplaceComparisons(testID uint, comparisons []grader.TComparison) error {
+	return replaceRows(repo.db, "test_id", testID, comparisons, func(c grader.TComparison) grader.TComparison {
+		return grader.TComparison{Path: c.Path, ObjectMode: c.ObjectMode, ArrayMode: c.ArrayMode, TestID: testID}
+	})
+}
+
+// saveSection stores a section with its dependencies and validates the project.
+func (repo *ProjectRepo) saveSection(sec *grader.Section) error {
+	if err := repo.Save(sec); err != nil {
+		return err
+	}
+	if err := repo.ReplaceSectionDependencies(sec.ID, sec.DependsOnIDs); err != nil {
+		return err
+	}
+	return repo.Validate(sec.ProjectID)
+}
+
+// saveScenario stores a scenario with its dependencies and validates the project.
+func (repo *ProjectRepo) saveScenario(projID uint, scn *grader.Scenario) error {
+	if err := repo.Save(scn); err != nil {
+		return err
+	}
+	if err := repo.ReplaceScenarioDependencies(scn.ID, scn.DependsOnIDs); err != nil {
+		return err
+	}
+	return repo.Validate(projID)
+}
+
+// saveTest stores a test with its headers, assertions, comparisons and dependencies and
+// validates the project.
+func (repo *ProjectRepo) saveTest(projID uint, test *grader.Test) error {
+	if err := repo.Save(test); err != nil {
+		return err
+	}
+	if err := repo.ReplaceRequestHeaders(test.ID, test.Request.Headers); err != nil {
+		return err
+	}
+	if err := repo.ReplaceResponseHeaders(test.ID, test.Response.Headers); err != nil {
+		return err
+	}
+	if err := repo.ReplaceAssertions(test.ID, test.Response.Assertions); err != nil {
+		return err
+	}
+	if err := repo.ReplaceComparisons(test.ID, test.Response.Comparisons); err != nil {
+		return err
+	}
+	if err := repo.ReplaceTestDependencies(test.ID, test.DependsOnIDs); err != nil {
+		return err
+	}
+	return repo.Validate(projID)
+}
+
+// replaceRows deletes the rows of type R whose ownerColumn is ownerID and inserts
+// one row built from each of items instead.
+func replaceRows[T any, R any](db *gorm.DB, ownerColumn string, ownerID uint, items []T, row func(T) R) error {
+	if err := db.Unscoped().Where(ownerColumn+" = ?", ownerID).Delete(new(R)).Error; err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	rows := make([]R, len(items))
+	for i, item := range items {
+		rows[i] = row(item)
+	}
+	return db.Omit(clause.Associations).Create(&rows).Error
+}
@@ -0,0 +1,265 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+)
+
+// ProjectService manages the Project→Section→Scenario→Test tree that gets graded.
+// Every write that can affect dependencies is validated in the same transaction,
+// so a project never ends up with a graph the grader would refuse.
+//
+// Projects belong to courses: members of a course see its projects, and only its
+// staff may change them.
+type ProjectService struct {
+	repo   ProjectRepo
+	access *course.Access
+	cfg    *config.AppConfig
+}
+
+func NewProjectService(repo ProjectRepo, access *course.Access, cfg *config.AppConfig) *ProjectService {
+	return &ProjectService{
+		repo:   repo,
+		access: access,
+		cfg:    cfg,
+	}
+}
+
+// List returns the projects of the courses viewer is enrolled in.
+func (s *ProjectService) List(viewer *user.User) ([]grader.Project, error) {
+	courseIDs, err := s.access.CourseIDs(viewer)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.repo.List(courseIDs)
+	return projects, translateError(err)
+}
+
+// Get returns a project with its whole tree.
+func (s *ProjectService) Get(viewer *user.User, id uint) (*grader.Project, error) {
+	proj, err := s.repo.FindById(id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	ok, err := s.access.IsMember(viewer, proj.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	proj, err = s.repo.FindTree(id)
+	return proj, translateError(err)
+}
+
+func (s *ProjectService) CreateProject(viewer *user.User, proj *grader.Project) error {
+	err := s.checkStaff(viewer, proj.CourseID)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: course %d not found", ErrInvalid, proj.CourseID)
+	}
+	if err != nil {
+		return err
+	}
+	return translateError(s.repo.Save(proj))
+}
+
+// UpdateProject changes a project's details and possibly moves it to another course,
+// which viewer must be on the staff of as well.
+func (s *ProjectService) UpdateProject(viewer *user.User, id uint, changes grader.Project) (*grader.Project, error) {
+	proj, err := s.editable(viewer, id)
+	if err != nil {
+		return nil, err
+	}
+	if changes.CourseID != 0 && changes.CourseID != proj.CourseID {
+		err := s.checkStaff(viewer, changes.CourseID)
+		if errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("%w: course %d not found", ErrInvalid, changes.CourseID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		proj.CourseID = changes.CourseID
+	}
+
+	proj.Name = changes.Name
+	proj.Due = changes.Due
+	return proj, translateError(s.repo.Save(proj))
+}
+
+func (s *ProjectService) DeleteProject(viewer *user.User, id uint) error {
+	proj, err := s.editable(viewer, id)
+	if err != nil {
+		return err
+	}
+	return translateError(s.repo.DeleteProject(proj))
+}
+
+func (s *ProjectService) CreateSection(viewer *user.User, projID uint, sec *grader.Section) error {
+	if _, err := s.editable(viewer, projID); err != nil {
+		return err
+	}
+	return translateError(s.repo.Transaction(func(tx *ProjectRepo) error {
+		if _, err := tx.FindById(projID); err != nil {
+			return err
+		}
+		sec.ID = 0
+		sec.ProjectID = projID
+		return tx.saveSection(sec)
+	}))
+}
+
+func (s *ProjectService) UpdateSection(viewer *user.User, projID, secID uint, changes grader.Section) (*grader.Section, error) {
+	if _, err := s.editable(viewer, projID); err != nil {
+		return nil, err
+	}
+	var sec *grader.Section
+	err := s.repo.Transaction(func(tx *ProjectRepo) error {
+		var err error
+		if sec, err = tx.FindSection(projID, secID); err != nil {
+			return err
+		}
+		sec.Name = changes.Name
+		sec.Points = changes.Points
+		sec.DependsOnIDs = changes.DependsOnIDs
+		return tx.saveSection(sec)
+	})
+	return sec, translateError(err)
+}
+
+func (s *ProjectService) DeleteSection(viewer *user.User, projID, secID uint) error {
+	if _, err := s.editable(viewer, projID); err != nil {
+		return err
+	}
+	return translateError(s.repo.Transaction(func(tx *ProjectRepo) error {
+		sec, err := tx.FindSection(projID, secID)
+		if err != nil {
+			return err
+		}
+		return tx.DeleteSection(sec)
+	}))
+}
+
+func (s *ProjectService) CreateScenario(viewer *user.User, projID, secID uint, scn *grader.Scenario) error {
+	if _, err := s.editable(viewer, projID); err != nil {
+		return err
+	}
+	return translateError(s.repo.Transaction(func(tx *ProjectRepo) error {
+		if _, err := tx.FindSection(projID, secID); err != nil {
+			return err
+		}
+		scn.ID = 0
+		scn.SectionID = secID
+		return tx.saveScenario(projID, scn)
+	}))
+}
+
+func (s *ProjectService) UpdateScenario(viewer *user.User, projID, secID, scnID uint, changes grader.Scenario) (*grader.Scenario, error) {
+	if _, err := s.editable(viewer, projID); err != nil {
+		return nil, err
+	}
+	var scn *grader.Scenario
+	err := s.repo.Transaction(func(tx *ProjectRepo) error {
+		var err error
+		if scn, err = tx.FindScenario(projID, secID, scnID); err != nil {
+			return err
+		}
+		scn.Name = changes.Name
+		scn.Points = changes.Points
+		scn.DependsOnIDs = changes.DependsOnIDs
+		return tx.saveScenario(projID, scn)
+	})
+	return scn, translateError(err)
+}
+
+func (s *ProjectService) DeleteScenario(viewer *user.User, projID, secID, scnID uint) error {
+	if _, err := s.editable(viewer, projID); err != nil {
+		return err
+	}
+	return translateError(s.repo.Transaction(func(tx *ProjectRepo) error {
+		scn, err := tx.FindScenario(projID, secID, scnID)
+		if err != nil {
+			return err
+		}
+		return tx.DeleteScenario(scn)
+	}))
+}
+
+func (s *ProjectService) CreateTest(viewer *user.User, projID, secID, scnID uint, test *grader.Test) error {
+	if _, err := s.editable(viewer, projID); err != nil {
+		return err
+	}
+	return translateError(s.repo.Transaction(func(tx *ProjectRepo) error {
+		if _, err := tx.FindScenario(projID, secID, scnID); err != nil {
+			return err
+		}
+		test.ID = 0
+		test.ScenarioID = scnID
+		return tx.saveTest(projID, test)
+	}))
+}
+
+func (s *ProjectService) UpdateTest(viewer *user.User, projID, secID, scnID, testID uint, changes grader.Test) (*grader.Test, error) {
+	if _, err := s.editable(viewer, projID); err != nil {
+		return nil, err
+	}
+	var test *grader.Test
+	err := s.repo.Transaction(func(tx *ProjectRepo) error {
+		var err error
+		if test, err = tx.FindTest(projID, secID, scnID, testID); err != nil {
+			return err
+		}
+		test.Name = changes.Name
+		test.Points = changes.Points
+		test.Request = changes.Request
+		test.Response = changes.Response
+		test.DependsOnIDs = changes.DependsOnIDs
+		return tx.saveTest(projID, test)
+	})
+	return test, translateError(err)
+}
+
+func (s *ProjectService) DeleteTest(viewer *user.User, projID, secID, scnID, testID uint) error {
+	if _, err := s.editable(viewer, projID); err != nil {
+		return err
+	}
+	return translateError(s.repo.Transaction(func(tx *ProjectRepo) error {
+		test, err := tx.FindTest(projID, secID, scnID, testID)
+		if err != nil {
+			return err
+		}
+		return tx.DeleteTest(test)
+	}))
+}
+
+// editable returns the project if viewer may change it.
+func (s *ProjectService) editable(viewer *user.User, projID uint) (*grader.Project, error) {
+	proj, err := s.repo.FindById(projID)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if err := s.checkStaff(viewer, proj.CourseID); err != nil {
+		return nil, err
+	}
+	return proj, nil
+}
+
+// checkStaff returns ErrNotFound if viewer isn't enrolled in the course, so that other
+// courses' projects stay hidden, and ErrForbidden if they're enrolled but not staff.
+func (s *ProjectService) checkStaff(viewer *user.User, courseID uint) error {
+	if ok, err := s.access.IsStaff(viewer, courseID); err != nil || ok {
+		return err
+	}
+	ok, err := s.access.IsMember(viewer, courseID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return ErrForbidden
+}
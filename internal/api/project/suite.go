@@ -0,0 +1,236 @@
+package project
+
+import (
+	"errors"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"gorm.io/gorm"
+)
+
+// ImportSuite creates the project a suite describes in the course, or updates the
+// course's project of the same name. It reports whether the project was created.
+func (s *ProjectService) ImportSuite(viewer *user.User, courseID uint, suite *grader.Suite) (*grader.Project, bool, error) {
+	if err := s.checkStaff(viewer, courseID); err != nil {
+		return nil, false, err
+	}
+	proj, created, err := s.repo.ImportSuite(courseID, suite)
+	return proj, created, translateError(err)
+}
+
+// ExportSuite writes a project viewer may see down as a suite.
+func (s *ProjectService) ExportSuite(viewer *user.User, projID uint) (*grader.Suite, error) {
+	proj, err := s.Get(viewer, projID)
+	if err != nil {
+		return nil, err
+	}
+	return grader.ExportSuite(proj), nil
+}
+
+func (repo *ProjectRepo) FindByName(courseID uint, name string) (*grader.Project, error) {
+	var proj grader.Project
+	result := repo.db.Where("course_id = ? AND name = ?", courseID, name).Order("id").First(&proj)
+	return &proj, result.Error
+}
+
+func (repo *ProjectRepo) ExportSuite(projID uint) (*grader.Suite, error) {
+	proj, err := repo.FindTree(projID)
+	if err != nil {
+		return nil, err
+	}
+	return grader.ExportSuite(proj), nil
+}
+
+// ImportSuite makes the course's project named like the suite match it, creating the
+// project if there is none. Sections, scenarios and tests are matched by name, so the
+// ones that stay keep their IDs, and with them the results that point at them; the
+// ones the suite doesn't have any more are deleted. New entries come after existing
+// ones when the project is exported again.
+func (repo *ProjectRepo) ImportSuite(courseID uint, suite *grader.Suite) (*grader.Project, bool, error) {
+	if err := suite.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	var proj *grader.Project
+	created := false
+	err := repo.Transaction(func(tx *ProjectRepo) error {
+		var err error
+		proj, err = tx.FindByName(courseID, suite.Name)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			proj, created = &grader.Project{CourseID: courseID}, true
+		} else if err != nil {
+			return err
+		}
+		proj.Name = suite.Name
+		proj.Due = suite.Due
+		if err := tx.Save(proj); err != nil {
+			return err
+		}
+
+		existing := []grader.Section{}
+		if !created {
+			tree, err := grader.LoadProject(tx.db, proj.ID)
+			if err != nil {
+				return err
+			}
+			existing = tree.Sections
+		}
+		if err := tx.importSections(proj.ID, existing, suite.Sections); err != nil {
+			return err
+		}
+		return tx.Validate(proj.ID)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	proj, err = repo.FindTree(proj.ID)
+	return proj, created, err
+}
+
+func (repo *ProjectRepo) importSections(projID uint, existing []grader.Section, sections []grader.SuiteSection) error {
+	byName := make(map[string]*grader.Section, len(existing))
+	for i := range existing {
+		byName[existing[i].Name] = &existing[i]
+	}
+
+	ids := make(map[string]uint, len(sections))
+	for _, ss := range sections {
+		sec, ok := byName[ss.Name]
+		if !ok {
+			sec = &grader.Section{ProjectID: projID}
+		}
+		delete(byName, ss.Name)
+		sec.Name = ss.Name
+		sec.Points = grader.SuitePoints(ss.Points)
+		if err := repo.Save(sec); err != nil {
+			return err
+		}
+		ids[ss.Name] = sec.ID
+		if err := repo.importScenarios(sec.ID, sec.Scenarios, ss.Scenarios); err != nil {
+			return err
+		}
+	}
+
+	for _, sec := range byName {
+		if err := repo.DeleteSection(sec); err != nil {
+			return err
+		}
+	}
+	for _, ss := range sections {
+		if err := repo.ReplaceSectionDependencies(ids[ss.Name], suiteDependencies(ids, ss.DependsOn)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *ProjectRepo) importScenarios(secID uint, existing []grader.Scenario, scenarios []grader.SuiteScenario) error {
+	byName := make(map[string]*grader.Scenario, len(existing))
+	for i := range existing {
+		byName[existing[i].Name] = &existing[i]
+	}
+
+	ids := make(map[string]uint, len(scenarios))
+	for _, ssc := range scenarios {
+		scn, ok := byName[ssc.Name]
+		if !ok {
+			scn = &grader.Scenario{SectionID: secID}
+		}
+		delete(byName, ssc.Name)
+		scn.Name = ssc.Name
+		scn.Points = grader.SuitePoints(ssc.Points)
+		if err := repo.Save(scn); err != nil {
+			return err
+		}
+		ids[ssc.Name] = scn.ID
+		if err := repo.importTests(scn.ID, scn.Tests, ssc.Tests); err != nil {
+			return err
+		}
+	}
+
+	for _, scn := range byName {
+		if err := repo.DeleteScenario(scn); err != nil {
+			return err
+		}
+	}
+	for _, ssc := range scenarios {
+		if err := repo.ReplaceScenarioDependencies(ids[ssc.Name], suiteDependencies(ids, ssc.DependsOn)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *ProjectRepo) importTests(scnID uint, existing []grader.Test, tests []grader.SuiteTest) error {
+	byName := make(map[string]*grader.Test, len(existing))
+	for i := range existing {
+		byName[existing[i].Name] = &existing[i]
+	}
+
+	ids := make(map[string]uint, len(tests))
+	for _, st := range tests {
+		test, ok := byName[st.Name]
+		if !ok {
+			test = &grader.Test{ScenarioID: scnID}
+		}
+		delete(byName, st.Name)
+		test.Name = st.Name
+		test.Points = grader.SuitePoints(st.Points)
+		test.Request = grader.TRequest{
+			Url:     st.Request.Url,
+			Method:  st.Request.Method,
+			ReqBody: string(st.Request.Body),
+		}
+		test.Response = st.Response.Model()
+		if err := repo.Save(test); err != nil {
+			return err
+		}
+		ids[st.Name] = test.ID
+
+		headers := make([]grader.THeader, len(st.Request.Headers))
+		for i, h := range st.Request.Headers {
+			headers[i] = grader.THeader{Key: h.Key, Value: h.Value}
+		}
+		if err := repo.ReplaceRequestHeaders(test.ID, headers); err != nil {
+			return err
+		}
+		resHeaders := make([]grader.TResHeader, len(st.Response.Headers))
+		for i, h := range st.Response.Headers {
+			resHeaders[i] = grader.TResHeader{Key: h.Key, Value: h.Value}
+		}
+		if err := repo.ReplaceResponseHeaders(test.ID, resHeaders); err != nil {
+			return err
+		}
+		assertions := make([]grader.TAssertion, len(st.Response.Assertions))
+		for i, expr := range st.Response.Assertions {
+			assertions[i] = grader.TAssertion{Expr: expr}
+		}
+		if err := repo.ReplaceAssertions(test.ID, assertions); err != nil {
+			return err
+		}
+		if err := repo.ReplaceComparisons(test.ID, test.Response.Comparisons); err != nil {
+			return err
+		}
+	}
+
+	for _, test := range byName {
+		if err := repo.DeleteTest(test); err != nil {
+			return err
+		}
+	}
+	for _, st := range tests {
+		if err := repo.ReplaceTestDependencies(ids[st.Name], suiteDependencies(ids, st.DependsOn)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func suiteDependencies(ids map[string]uint, names []string) []uint {
+	deps := make([]uint, len(names))
+	for i, name := range names {
+		deps[i] = ids[name]
+	}
+	return deps
+}
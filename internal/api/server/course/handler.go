@@ -0,0 +1,202 @@
+package course
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/rest"
+)
+
+type Handler struct {
+	Service *course.CourseService
+}
+
+func NewHandler(svc *course.CourseService) *Handler {
+	return &Handler{
+		Service: svc,
+	}
+}
+
+func (h *Handler) ListCourses(c *gin.Context) {
+	curUser := c.MustGet("curUser").(*user.User)
+	courses, err := h.Service.List(curUser)
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to list courses", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"courses": courses,
+	})
+}
+
+func (h *Handler) CreateCourse(c *gin.Context) {
+	var input CourseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	crs := course.Course{Name: input.Name}
+	if err := h.Service.Create(curUser, &crs); err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to create course", err)
+		return
+	}
+	rest.RespondCreated(c, gin.H{
+		"course": crs,
+	})
+}
+
+// ImportRoster takes the roster either as a "roster" file in a multipart form or as
+// the request body, and answers with the credentials sheet as a CSV download.
+func (h *Handler) ImportRoster(c *gin.Context) {
+	courseID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid course id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.CheckStaff(curUser, uint(courseID)); err != nil {
+		respondServiceError(c, "Failed to import roster", err)
+		return
+	}
+
+	roster, err := rosterReader(c)
+	if rest.TooLarge(err) {
+		rest.RespondError(c, http.StatusRequestEntityTooLarge, "Roster is too large", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid roster", err)
+		return
+	}
+	defer roster.Close()
+
+	entries, err := course.ParseRoster(roster)
+	if err != nil {
+		rest.RespondError(c, http.StatusUnprocessableEntity, "Invalid roster", err)
+		return
+	}
+	creds, err := h.Service.ImportRoster(uint(courseID), entries)
+	if err != nil {
+		respondServiceError(c, "Failed to import roster", err)
+		return
+	}
+
+	var sheet bytes.Buffer
+	if err := course.WriteCredentials(&sheet, creds); err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to write credentials", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="course-%d-credentials.csv"`, courseID))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", sheet.Bytes())
+}
+
+func (h *Handler) ListMembers(c *gin.Context) {
+	courseID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid course id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	members, err := h.Service.Members(curUser, uint(courseID))
+	if err != nil {
+		respondServiceError(c, "Failed to list members", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"members": members,
+	})
+}
+
+// SaveMember enrolls a user in the course, or changes their role in it.
+func (h *Handler) SaveMember(c *gin.Context) {
+	courseID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid course id", err)
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid user id", err)
+		return
+	}
+	var input MemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	enrollment, err := h.Service.SaveMember(curUser, uint(courseID), uint(userID), input.Role)
+	if err != nil {
+		respondServiceError(c, "Failed to save member", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"enrollment": enrollment,
+	})
+}
+
+func (h *Handler) RemoveMember(c *gin.Context) {
+	courseID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid course id", err)
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("userID"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid user id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.RemoveMember(curUser, uint(courseID), uint(userID)); err != nil {
+		respondServiceError(c, "Failed to remove member", err)
+		return
+	}
+	rest.RespondNoContent(c)
+}
+
+// respondServiceError maps the service's errors to HTTP statuses.
+func respondServiceError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, course.ErrNotFound):
+		rest.RespondError(c, http.StatusNotFound, "Course not found", err)
+	case errors.Is(err, course.ErrUserNotFound):
+		rest.RespondError(c, http.StatusNotFound, "User not found", err)
+	case errors.Is(err, course.ErrForbidden):
+		rest.RespondError(c, http.StatusForbidden, "Permission denied", err)
+	default:
+		rest.RespondError(c, http.StatusInternalServerError, message, err)
+	}
+}
+
+// maxRosterSize is the largest roster request ImportRoster accepts, in bytes.
+const maxRosterSize = 8 << 20
+
+// rosterReader returns the roster in the request. A roster sent as the body is read
+// whole, so that one that's too large is told apart from one that's malformed.
+func rosterReader(c *gin.Context) (io.ReadCloser, error) {
+	rest.LimitBody(c, maxRosterSize)
+	if c.ContentType() != "multipart/form-data" {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	file, err := c.FormFile("roster")
+	if err != nil {
+		return nil, err
+	}
+	return file.Open()
+}
@@ -0,0 +1,11 @@
+package course
+
+import "github.com/sinasadeghi83/aut-grader/internal/api/course"
+
+type CourseInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type MemberInput struct {
+	Role course.MemberRole `json:"role" binding:"required,oneof=student staff"`
+}
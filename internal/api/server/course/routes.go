@@ -0,0 +1,30 @@
+package course
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"gorm.io/gorm"
+)
+
+func RegisterHandler(db *gorm.DB, cfg *config.AppConfig) *Handler {
+	users := user.NewUserService(*user.NewUserRepo(db), cfg)
+	repo := course.NewCourseRepo(db)
+	svc := course.NewCourseService(*repo, course.NewAccess(db), users, cfg)
+	return NewHandler(svc)
+}
+
+// RegisterRoutes expects router to already require authentication; authorize
+// builds the middleware that checks the current user's permissions.
+func (handler *Handler) RegisterRoutes(router *gin.RouterGroup, authorize func(...user.Permission) gin.HandlerFunc) {
+	courses := router.Group("/courses")
+	courses.GET("", authorize(user.PermViewProjects), handler.ListCourses)
+
+	courses = courses.Group("", authorize(user.PermManageCourses))
+	courses.POST("", handler.CreateCourse)
+	courses.POST("/:id/roster", handler.ImportRoster)
+	courses.GET("/:id/members", handler.ListMembers)
+	courses.PUT("/:id/members/:userID", handler.SaveMember)
+	courses.DELETE("/:id/members/:userID", handler.RemoveMember)
+}
@@ -0,0 +1,165 @@
+package grading
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/grading"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/rest"
+)
+
+type Handler struct {
+	Service *grading.GradingService
+}
+
+func NewHandler(svc *grading.GradingService) *Handler {
+	return &Handler{
+		Service: svc,
+	}
+}
+
+// Grade queues grading of the caller's active submission.
+func (h *Handler) Grade(c *gin.Context) {
+	projID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid project id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	job, err := h.Service.Enqueue(curUser, uint(projID))
+	if errors.Is(err, grading.ErrProjectNotFound) {
+		rest.RespondError(c, http.StatusNotFound, "Project not found", err)
+		return
+	}
+	if errors.Is(err, grading.ErrNoSubmission) {
+		rest.RespondError(c, http.StatusConflict, "Submit a base URL before grading", err)
+		return
+	}
+	if errors.Is(err, grading.ErrAlreadyQueued) {
+		rest.RespondError(c, http.StatusConflict, "Wait for the current grading to finish", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to queue grading", err)
+		return
+	}
+
+	rest.RespondAccepted(c, gin.H{
+		"job": job,
+	})
+}
+
+func (h *Handler) GetResult(c *gin.Context) {
+	resultID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid result id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	result, err := h.Service.FindResult(uint(resultID), curUser)
+	if errors.Is(err, grading.ErrNotFound) {
+		rest.RespondError(c, http.StatusNotFound, "Result not found", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to load result", err)
+		return
+	}
+
+	rest.RespondOK(c, gin.H{
+		"result": result,
+	})
+}
+
+func (h *Handler) ListResults(c *gin.Context) {
+	projID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid project id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	results, err := h.Service.ListResults(curUser, uint(projID))
+	if errors.Is(err, grading.ErrProjectNotFound) {
+		rest.RespondError(c, http.StatusNotFound, "Project not found", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to list results", err)
+		return
+	}
+
+	rest.RespondOK(c, gin.H{
+		"results": results,
+	})
+}
+
+// Gradebook answers with the project's gradebook as JSON, or as a CSV download
+// with ?format=csv.
+func (h *Handler) Gradebook(c *gin.Context) {
+	projID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid project id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	proj, rows, err := h.Service.Gradebook(curUser, uint(projID))
+	if errors.Is(err, grading.ErrNotFound) {
+		rest.RespondError(c, http.StatusNotFound, "Project not found", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to load gradebook", err)
+		return
+	}
+
+	if c.Query("format") != "csv" {
+		rest.RespondOK(c, gin.H{
+			"project":   proj,
+			"gradebook": rows,
+		})
+		return
+	}
+	var sheet bytes.Buffer
+	if err := grading.WriteGradebook(&sheet, rows); err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to write gradebook", err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="project-%d-gradebook.csv"`, proj.ID))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", sheet.Bytes())
+}
+
+func (h *Handler) CancelResult(c *gin.Context) {
+	resultID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid result id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	err = h.Service.Cancel(uint(resultID), curUser)
+	if errors.Is(err, grading.ErrNotFound) {
+		rest.RespondError(c, http.StatusNotFound, "Result not found", err)
+		return
+	}
+	if errors.Is(err, grading.ErrNotCancellable) {
+		rest.RespondError(c, http.StatusConflict, "Grading can't be cancelled", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to cancel grading", err)
+		return
+	}
+
+	rest.RespondAccepted(c, gin.H{
+		"result_id": resultID,
+	})
+}
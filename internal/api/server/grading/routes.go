@@ -0,0 +1,26 @@
+package grading
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/grading"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"gorm.io/gorm"
+)
+
+func RegisterHandler(db *gorm.DB, cfg *config.AppConfig) *Handler {
+	repo := grading.NewJobRepo(db)
+	svc := grading.NewGradingService(*repo, course.NewAccess(db), cfg)
+	return NewHandler(svc)
+}
+
+// RegisterRoutes expects router to already require authentication; authorize
+// builds the middleware that checks the current user's permissions.
+func (handler *Handler) RegisterRoutes(router *gin.RouterGroup, authorize func(...user.Permission) gin.HandlerFunc) {
+	router.POST("/projects/:id/grade", authorize(user.PermGrade), handler.Grade)
+	router.GET("/projects/:id/results", handler.ListResults)
+	router.GET("/projects/:id/gradebook", authorize(user.PermAllResults), handler.Gradebook)
+	router.GET("/results/:id", handler.GetResult)
+	router.DELETE("/results/:id", handler.CancelResult)
+}
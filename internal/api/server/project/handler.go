@@ -0,0 +1,303 @@
+package project
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/project"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/rest"
+)
+
+type Handler struct {
+	Service *project.ProjectService
+}
+
+func NewHandler(svc *project.ProjectService) *Handler {
+	return &Handler{
+		Service: svc,
+	}
+}
+
+func (h *Handler) ListProjects(c *gin.Context) {
+	curUser := c.MustGet("curUser").(*user.User)
+	projects, err := h.Service.List(curUser)
+	if err != nil {
+		respondServiceError(c, "Failed to list projects", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"projects": projects,
+	})
+}
+
+func (h *Handler) GetProject(c *gin.Context) {
+	projID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	proj, err := h.Service.Get(curUser, projID)
+	if err != nil {
+		respondServiceError(c, "Failed to load project", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"project": proj,
+	})
+}
+
+func (h *Handler) CreateProject(c *gin.Context) {
+	var input ProjectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	proj := input.Model()
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.CreateProject(curUser, &proj); err != nil {
+		respondServiceError(c, "Failed to create project", err)
+		return
+	}
+	rest.RespondCreated(c, gin.H{
+		"project": proj,
+	})
+}
+
+func (h *Handler) UpdateProject(c *gin.Context) {
+	projID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	var input ProjectInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	proj, err := h.Service.UpdateProject(curUser, projID, input.Model())
+	if err != nil {
+		respondServiceError(c, "Failed to update project", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"project": proj,
+	})
+}
+
+func (h *Handler) DeleteProject(c *gin.Context) {
+	projID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.DeleteProject(curUser, projID); err != nil {
+		respondServiceError(c, "Failed to delete project", err)
+		return
+	}
+	rest.RespondNoContent(c)
+}
+
+func (h *Handler) CreateSection(c *gin.Context) {
+	projID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	var input SectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	sec := input.Model()
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.CreateSection(curUser, projID, &sec); err != nil {
+		respondServiceError(c, "Failed to create section", err)
+		return
+	}
+	rest.RespondCreated(c, gin.H{
+		"section": sec,
+	})
+}
+
+func (h *Handler) UpdateSection(c *gin.Context) {
+	ids, ok := idParams(c, "id", "sectionID")
+	if !ok {
+		return
+	}
+	var input SectionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	sec, err := h.Service.UpdateSection(curUser, ids[0], ids[1], input.Model())
+	if err != nil {
+		respondServiceError(c, "Failed to update section", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"section": sec,
+	})
+}
+
+func (h *Handler) DeleteSection(c *gin.Context) {
+	ids, ok := idParams(c, "id", "sectionID")
+	if !ok {
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.DeleteSection(curUser, ids[0], ids[1]); err != nil {
+		respondServiceError(c, "Failed to delete section", err)
+		return
+	}
+	rest.RespondNoContent(c)
+}
+
+func (h *Handler) CreateScenario(c *gin.Context) {
+	ids, ok := idParams(c, "id", "sectionID")
+	if !ok {
+		return
+	}
+	var input ScenarioInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	scn := input.Model()
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.CreateScenario(curUser, ids[0], ids[1], &scn); err != nil {
+		respondServiceError(c, "Failed to create scenario", err)
+		return
+	}
+	rest.RespondCreated(c, gin.H{
+		"scenario": scn,
+	})
+}
+
+func (h *Handler) UpdateScenario(c *gin.Context) {
+	ids, ok := idParams(c, "id", "sectionID", "scenarioID")
+	if !ok {
+		return
+	}
+	var input ScenarioInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	scn, err := h.Service.UpdateScenario(curUser, ids[0], ids[1], ids[2], input.Model())
+	if err != nil {
+		respondServiceError(c, "Failed to update scenario", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"scenario": scn,
+	})
+}
+
+func (h *Handler) DeleteScenario(c *gin.Context) {
+	ids, ok := idParams(c, "id", "sectionID", "scenarioID")
+	if !ok {
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.DeleteScenario(curUser, ids[0], ids[1], ids[2]); err != nil {
+		respondServiceError(c, "Failed to delete scenario", err)
+		return
+	}
+	rest.RespondNoContent(c)
+}
+
+func (h *Handler) CreateTest(c *gin.Context) {
+	ids, ok := idParams(c, "id", "sectionID", "scenarioID")
+	if !ok {
+		return
+	}
+	var input TestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	test := input.Model()
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.CreateTest(curUser, ids[0], ids[1], ids[2], &test); err != nil {
+		respondServiceError(c, "Failed to create test", err)
+		return
+	}
+	rest.RespondCreated(c, gin.H{
+		"test": test,
+	})
+}
+
+func (h *Handler) UpdateTest(c *gin.Context) {
+	ids, ok := idParams(c, "id", "sectionID", "scenarioID", "testID")
+	if !ok {
+		return
+	}
+	var input TestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	test, err := h.Service.UpdateTest(curUser, ids[0], ids[1], ids[2], ids[3], input.Model())
+	if err != nil {
+		respondServiceError(c, "Failed to update test", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"test": test,
+	})
+}
+
+func (h *Handler) DeleteTest(c *gin.Context) {
+	ids, ok := idParams(c, "id", "sectionID", "scenarioID", "testID")
+	if !ok {
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.DeleteTest(curUser, ids[0], ids[1], ids[2], ids[3]); err != nil {
+		respondServiceError(c, "Failed to delete test", err)
+		return
+	}
+	rest.RespondNoContent(c)
+}
+
+// idParam parses a numeric path parameter, responding with 400 when it isn't one.
+func idParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid "+name, err)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// idParams parses several numeric path parameters, in order.
+func idParams(c *gin.Context, names ...string) ([]uint, bool) {
+	ids := make([]uint, len(names))
+	for i, name := range names {
+		id, ok := idParam(c, name)
+		if !ok {
+			return nil, false
+		}
+		ids[i] = id
+	}
+	return ids, true
+}
+
+// respondServiceError maps the service's errors to HTTP statuses.
+func respondServiceError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, project.ErrNotFound):
+		rest.RespondError(c, http.StatusNotFound, "Not found", err)
+	case errors.Is(err, project.ErrForbidden):
+		rest.RespondError(c, http.StatusForbidden, "Permission denied", err)
+	case errors.Is(err, project.ErrInvalid):
+		rest.RespondError(c, http.StatusUnprocessableEntity, message, err)
+	default:
+		rest.RespondError(c, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,125 @@
+package project
+
+import (
+	"time"
+
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+)
+
+// ProjectInput creates a project in CourseID, or on update moves it there if set.
+type ProjectInput struct {
+	Name     string    `json:"name" binding:"required,max=60"`
+	Due      time.Time `json:"due"`
+	CourseID uint      `json:"course_id"`
+}
+
+type SectionInput struct {
+	Name         string   `json:"name" binding:"required,max=60"`
+	Points       *float64 `json:"points" binding:"omitempty,gte=0"`
+	DependsOnIDs []uint   `json:"depends_on_ids"`
+}
+
+type ScenarioInput struct {
+	Name         string   `json:"name" binding:"required,max=60"`
+	Points       *float64 `json:"points" binding:"omitempty,gte=0"`
+	DependsOnIDs []uint   `json:"depends_on_ids"`
+}
+
+type HeaderInput struct {
+	Key   string `json:"key" binding:"required,max=300"`
+	Value string `json:"value" binding:"max=300"`
+}
+
+type RequestInput struct {
+	Url     string        `json:"url" binding:"required,max=2048"`
+	Method  string        `json:"method" binding:"required,oneof=GET POST PUT DELETE PATCH"`
+	Headers []HeaderInput `json:"headers" binding:"dive"`
+	Body    string        `json:"body"`
+}
+
+// ComparisonInput changes how the part of the expected body at Path is compared.
+type ComparisonInput struct {
+	Path       string `json:"path" binding:"required,max=300"`
+	ObjectMode string `json:"object_mode" binding:"omitempty,oneof=subset strict"`
+	ArrayMode  string `json:"array_mode" binding:"omitempty,oneof=unordered ordered contains"`
+}
+
+// ResponseInput is what a test expects back. Assertions are written as "path op value",
+// e.g. "$.items.length > 0".
+type ResponseInput struct {
+	StatusCode  uint              `json:"status_code" binding:"required,min=100,max=599"`
+	Headers     []HeaderInput     `json:"headers" binding:"dive"`
+	Body        string            `json:"body"`
+	ObjectMode  string            `json:"object_mode" binding:"omitempty,oneof=subset strict"`
+	ArrayMode   string            `json:"array_mode" binding:"omitempty,oneof=unordered ordered contains"`
+	Comparisons []ComparisonInput `json:"comparisons" binding:"dive"`
+	Assertions  []string          `json:"assertions" binding:"dive,required,max=300"`
+}
+
+type TestInput struct {
+	Name         string        `json:"name" binding:"required,max=60"`
+	Points       *float64      `json:"points" binding:"omitempty,gte=0"`
+	Request      RequestInput  `json:"request" binding:"required"`
+	Response     ResponseInput `json:"response" binding:"required"`
+	DependsOnIDs []uint        `json:"depends_on_ids"`
+}
+
+func (in ProjectInput) Model() grader.Project {
+	return grader.Project{
+		Name:     in.Name,
+		Due:      in.Due,
+		CourseID: in.CourseID,
+	}
+}
+
+func (in SectionInput) Model() grader.Section {
+	return grader.Section{
+		Name:         in.Name,
+		Points:       grader.SuitePoints(in.Points),
+		DependsOnIDs: in.DependsOnIDs,
+	}
+}
+
+func (in ScenarioInput) Model() grader.Scenario {
+	return grader.Scenario{
+		Name:         in.Name,
+		Points:       grader.SuitePoints(in.Points),
+		DependsOnIDs: in.DependsOnIDs,
+	}
+}
+
+func (in TestInput) Model() grader.Test {
+	test := grader.Test{
+		Name:   in.Name,
+		Points: grader.SuitePoints(in.Points),
+		Request: grader.TRequest{
+			Url:     in.Request.Url,
+			Method:  in.Request.Method,
+			ReqBody: in.Request.Body,
+		},
+		Response: grader.TResponse{
+			StatusCode: in.Response.StatusCode,
+			ResBody:    in.Response.Body,
+			ObjectMode: grader.ObjectMode(in.Response.ObjectMode),
+			ArrayMode:  grader.ArrayMode(in.Response.ArrayMode),
+		},
+		DependsOnIDs: in.DependsOnIDs,
+	}
+	for _, h := range in.Request.Headers {
+		test.Request.Headers = append(test.Request.Headers, grader.THeader{Key: h.Key, Value: h.Value})
+	}
+	for _, h := range in.Response.Headers {
+		test.Response.Headers = append(test.Response.Headers, grader.TResHeader{Key: h.Key, Value: h.Value})
+	}
+	for _, c := range in.Response.Comparisons {
+		test.Response.Comparisons = append(test.Response.Comparisons, grader.TComparison{
+			Path:       c.Path,
+			ObjectMode: grader.ObjectMode(c.ObjectMode),
+			ArrayMode:  grader.ArrayMode(c.ArrayMode),
+		})
+	}
+	for _, expr := range in.Response.Assertions {
+		test.Response.Assertions = append(test.Response.Assertions, grader.TAssertion{Expr: expr})
+	}
+	return test
+}
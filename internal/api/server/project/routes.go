@@ -0,0 +1,44 @@
+package project
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/project"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"gorm.io/gorm"
+)
+
+func RegisterHandler(db *gorm.DB, cfg *config.AppConfig) *Handler {
+	repo := project.NewProjectRepo(db)
+	svc := project.NewProjectService(*repo, course.NewAccess(db), cfg)
+	return NewHandler(svc)
+}
+
+// RegisterRoutes expects router to already require authentication; authorize
+// builds the middleware that checks the current user's permissions.
+func (handler *Handler) RegisterRoutes(router *gin.RouterGroup, authorize func(...user.Permission) gin.HandlerFunc) {
+	projects := router.Group("/projects")
+	projects.GET("", authorize(user.PermViewProjects), handler.ListProjects)
+	projects.GET("/:id", authorize(user.PermViewProjects), handler.GetProject)
+	projects.GET("/:id/suite", authorize(user.PermViewProjects), handler.ExportSuite)
+
+	router.POST("/courses/:id/suites", authorize(user.PermEditProjects), handler.ImportSuite)
+
+	projects = projects.Group("", authorize(user.PermEditProjects))
+	projects.POST("", handler.CreateProject)
+	projects.PUT("/:id", handler.UpdateProject)
+	projects.DELETE("/:id", handler.DeleteProject)
+
+	projects.POST("/:id/sections", handler.CreateSection)
+	projects.PUT("/:id/sections/:sectionID", handler.UpdateSection)
+	projects.DELETE("/:id/sections/:sectionID", handler.DeleteSection)
+
+	projects.POST("/:id/sections/:sectionID/scenarios", handler.CreateScenario)
+	projects.PUT("/:id/sections/:sectionID/scenarios/:scenarioID", handler.UpdateScenario)
+	projects.DELETE("/:id/sections/:sectionID/scenarios/:scenarioID", handler.DeleteScenario)
+
+	projects.POST("/:id/sections/:sectionID/scenarios/:scenarioID/tests", handler.CreateTest)
+	projects.PUT("/:id/sections/:sectionID/scenarios/:scenarioID/tests/:testID", handler.UpdateTest)
+	projects.DELETE("/:id/sections/:sectionID/scenarios/:scenarioID/tests/:testID", handler.DeleteTest)
+}
@@ -0,0 +1,107 @@
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/rest"
+)
+
+// ImportSuite creates or updates a project of the course from a YAML or JSON suite,
+// sent either as a "suite" file in a multipart form or as the request body.
+func (h *Handler) ImportSuite(c *gin.Context) {
+	courseID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	file, err := suiteReader(c)
+	if rest.TooLarge(err) {
+		rest.RespondError(c, http.StatusRequestEntityTooLarge, "Suite is too large", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid suite", err)
+		return
+	}
+	defer file.Close()
+
+	suite, err := grader.ReadSuite(file)
+	if err != nil {
+		rest.RespondError(c, http.StatusUnprocessableEntity, "Invalid suite", err)
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+	proj, created, err := h.Service.ImportSuite(curUser, courseID, suite)
+	if err != nil {
+		respondServiceError(c, "Failed to import suite", err)
+		return
+	}
+	if created {
+		rest.RespondCreated(c, gin.H{
+			"project": proj,
+		})
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"project": proj,
+	})
+}
+
+// ExportSuite answers with the project as a YAML suite download, or a JSON one with ?format=json.
+func (h *Handler) ExportSuite(c *gin.Context) {
+	projID, ok := idParam(c, "id")
+	if !ok {
+		return
+	}
+	format := grader.SuiteFormat(c.DefaultQuery("format", string(grader.SuiteYAML)))
+	if format != grader.SuiteYAML && format != grader.SuiteJSON {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid format", errors.New("format must be yaml or json"))
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	suite, err := h.Service.ExportSuite(curUser, projID)
+	if err != nil {
+		respondServiceError(c, "Failed to export suite", err)
+		return
+	}
+	var file bytes.Buffer
+	if err := grader.WriteSuite(&file, suite, format); err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to write suite", err)
+		return
+	}
+
+	contentType := "application/yaml; charset=utf-8"
+	if format == grader.SuiteJSON {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="project-%d.%s"`, projID, format))
+	c.Data(http.StatusOK, contentType, file.Bytes())
+}
+
+// maxSuiteSize is the largest suite request ImportSuite accepts, in bytes.
+const maxSuiteSize = 8 << 20
+
+// suiteReader returns the suite in the request. A suite sent as the body is read
+// whole, so that one that's too large is told apart from one that's malformed.
+func suiteReader(c *gin.Context) (io.ReadCloser, error) {
+	rest.LimitBody(c, maxSuiteSize)
+	if c.ContentType() != "multipart/form-data" {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	file, err := c.FormFile("suite")
+	if err != nil {
+		return nil, err
+	}
+	return file.Open()
+}
@@ -1,20 +1,28 @@
 package server
 
 import (
+	"context"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	gradingapi "github.com/sinasadeghi83/aut-grader/internal/api/grading"
+	"github.com/sinasadeghi83/aut-grader/internal/api/server/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/server/grading"
+	"github.com/sinasadeghi83/aut-grader/internal/api/server/project"
+	"github.com/sinasadeghi83/aut-grader/internal/api/server/submission"
+	"github.com/sinasadeghi83/aut-grader/internal/api/server/team"
 	"github.com/sinasadeghi83/aut-grader/internal/api/server/user"
 	"github.com/sinasadeghi83/aut-grader/pkg/config"
 	"gorm.io/gorm"
 )
 
 type Server struct {
-	Engine *gin.Engine
-	Addr   string
-	DB     *gorm.DB
-	Config *config.AppConfig
+	Engine  *gin.Engine
+	Addr    string
+	DB      *gorm.DB
+	Config  *config.AppConfig
+	Workers *gradingapi.WorkerPool
 }
 
 func NewServer(addr string, db *gorm.DB, cfg *config.AppConfig) *Server {
@@ -32,6 +40,21 @@ func (s *Server) SetupRoutes() {
 	authHandler := user.RegisterHandler(s.DB, s.Config)
 	authHandler.RegisterRoutes(api.Group("/auth"))
 
+	authorized := api.Group("", authHandler.CheckAuth)
+	authHandler.RegisterAccountRoutes(authorized)
+	authHandler.RegisterUserRoutes(authorized)
+	gradingHandler := grading.RegisterHandler(s.DB, s.Config)
+	gradingHandler.RegisterRoutes(authorized, authHandler.Authorize)
+	s.Workers = gradingapi.NewWorkerPool(gradingHandler.Service, s.Config.GraderWorkers)
+	projectHandler := project.RegisterHandler(s.DB, s.Config)
+	projectHandler.RegisterRoutes(authorized, authHandler.Authorize)
+	courseHandler := course.RegisterHandler(s.DB, s.Config)
+	courseHandler.RegisterRoutes(authorized, authHandler.Authorize)
+	teamHandler := team.RegisterHandler(s.DB, s.Config)
+	teamHandler.RegisterRoutes(authorized, authHandler.Authorize)
+	submissionHandler := submission.RegisterHandler(s.DB, s.Config)
+	submissionHandler.RegisterRoutes(authorized, authHandler.Authorize)
+
 	//Health Check
 	s.Engine.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -41,6 +64,13 @@ func (s *Server) SetupRoutes() {
 }
 
 func (s *Server) Start() error {
+	if s.Workers != nil {
+		if err := s.Workers.Recover(); err != nil {
+			return err
+		}
+		s.Workers.Start(context.Background())
+	}
+
 	log.Printf("Server listening on %s", s.Addr)
 	return s.Engine.Run(s.Addr)
 }
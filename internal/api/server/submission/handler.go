@@ -0,0 +1,107 @@
+package submission
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/submission"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/rest"
+)
+
+// multipartOverhead is how much bigger than the artifact an upload's request may be,
+// for the form's boundaries and headers.
+const multipartOverhead = 1 << 20
+
+type Handler struct {
+	Service *submission.SubmissionService
+	// MaxSize is the largest artifact that may be uploaded, in bytes.
+	MaxSize int64
+}
+
+func NewHandler(svc *submission.SubmissionService, maxSize int64) *Handler {
+	return &Handler{
+		Service: svc,
+		MaxSize: maxSize,
+	}
+}
+
+// Submit registers the base URL the caller, or their team, deployed the project at,
+// given as JSON, or uploads an "artifact" file in a multipart form for the grader to run.
+func (h *Handler) Submit(c *gin.Context) {
+	projID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid project id", err)
+		return
+	}
+	curUser := c.MustGet("curUser").(*user.User)
+
+	var sub *submission.Submission
+	rest.LimitBody(c, h.MaxSize+multipartOverhead)
+	if c.ContentType() == "multipart/form-data" {
+		file, err := c.FormFile("artifact")
+		if rest.TooLarge(err) {
+			rest.RespondError(c, http.StatusRequestEntityTooLarge, "Submission is too large", err)
+			return
+		}
+		if err != nil {
+			rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+			return
+		}
+		content, err := file.Open()
+		if err != nil {
+			rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+			return
+		}
+		defer content.Close()
+		sub, err = h.Service.Upload(curUser, uint(projID), file.Filename, content)
+	} else {
+		var input SubmissionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+			return
+		}
+		sub, err = h.Service.Submit(curUser, uint(projID), input.BaseUrl)
+	}
+
+	switch {
+	case errors.Is(err, submission.ErrProjectNotFound):
+		rest.RespondError(c, http.StatusNotFound, "Project not found", err)
+	case errors.Is(err, submission.ErrUploadsDisabled):
+		rest.RespondError(c, http.StatusForbidden, "Uploading submissions is disabled", err)
+	case errors.Is(err, submission.ErrTooLarge):
+		rest.RespondError(c, http.StatusRequestEntityTooLarge, "Submission is too large", err)
+	case errors.Is(err, submission.ErrUnsupported):
+		rest.RespondError(c, http.StatusUnprocessableEntity, "Unsupported submission", err)
+	case err != nil:
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to submit", err)
+	default:
+		rest.RespondCreated(c, gin.H{
+			"submission": sub,
+		})
+	}
+}
+
+func (h *Handler) History(c *gin.Context) {
+	projID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid project id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	subs, err := h.Service.History(curUser, uint(projID))
+	if errors.Is(err, submission.ErrProjectNotFound) {
+		rest.RespondError(c, http.StatusNotFound, "Project not found", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to load submissions", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"submissions": subs,
+	})
+}
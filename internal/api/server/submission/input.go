@@ -0,0 +1,5 @@
+package submission
+
+type SubmissionInput struct {
+	BaseUrl string `json:"base_url" binding:"required,url,max=2048"`
+}
@@ -0,0 +1,25 @@
+package submission
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/submission"
+	"github.com/sinasadeghi83/aut-grader/internal/api/team"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"gorm.io/gorm"
+)
+
+func RegisterHandler(db *gorm.DB, cfg *config.AppConfig) *Handler {
+	repo := submission.NewSubmissionRepo(db)
+	svc := submission.NewSubmissionService(*repo, team.NewTeamRepo(db), course.NewAccess(db), cfg)
+	return NewHandler(svc, cfg.SubmissionMaxSize)
+}
+
+// RegisterRoutes expects router to already require authentication; authorize
+// builds the middleware that checks the current user's permissions.
+func (handler *Handler) RegisterRoutes(router *gin.RouterGroup, authorize func(...user.Permission) gin.HandlerFunc) {
+	submissions := router.Group("/projects/:id/submissions", authorize(user.PermGrade))
+	submissions.GET("", handler.History)
+	submissions.POST("", handler.Submit)
+}
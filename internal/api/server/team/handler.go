@@ -0,0 +1,125 @@
+package team
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/team"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/rest"
+)
+
+type Handler struct {
+	Service *team.TeamService
+}
+
+func NewHandler(svc *team.TeamService) *Handler {
+	return &Handler{
+		Service: svc,
+	}
+}
+
+func (h *Handler) ListTeams(c *gin.Context) {
+	projID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid project id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	teams, err := h.Service.List(curUser, uint(projID))
+	if err != nil {
+		respondServiceError(c, "Failed to list teams", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"teams": teams,
+	})
+}
+
+func (h *Handler) CreateTeam(c *gin.Context) {
+	projID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid project id", err)
+		return
+	}
+	var input TeamInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	t, err := h.Service.Create(curUser, uint(projID), input.Name, input.MemberIDs)
+	if err != nil {
+		respondServiceError(c, "Failed to create team", err)
+		return
+	}
+	rest.RespondCreated(c, gin.H{
+		"team": t,
+	})
+}
+
+func (h *Handler) UpdateTeam(c *gin.Context) {
+	projID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid project id", err)
+		return
+	}
+	teamID, err := strconv.ParseUint(c.Param("teamID"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid team id", err)
+		return
+	}
+	var input TeamInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	t, err := h.Service.Update(curUser, uint(projID), uint(teamID), input.Name, input.MemberIDs)
+	if err != nil {
+		respondServiceError(c, "Failed to update team", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"team": t,
+	})
+}
+
+func (h *Handler) DeleteTeam(c *gin.Context) {
+	projID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid project id", err)
+		return
+	}
+	teamID, err := strconv.ParseUint(c.Param("teamID"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid team id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	if err := h.Service.Delete(curUser, uint(projID), uint(teamID)); err != nil {
+		respondServiceError(c, "Failed to delete team", err)
+		return
+	}
+	rest.RespondNoContent(c)
+}
+
+// respondServiceError maps the service's errors to HTTP statuses.
+func respondServiceError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, team.ErrNotFound):
+		rest.RespondError(c, http.StatusNotFound, "Not found", err)
+	case errors.Is(err, team.ErrForbidden):
+		rest.RespondError(c, http.StatusForbidden, "Permission denied", err)
+	case errors.Is(err, team.ErrInvalidMembers):
+		rest.RespondError(c, http.StatusUnprocessableEntity, message, err)
+	default:
+		rest.RespondError(c, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,6 @@
+package team
+
+type TeamInput struct {
+	Name      string `json:"name" binding:"required,max=60"`
+	MemberIDs []uint `json:"member_ids" binding:"required,min=1"`
+}
@@ -0,0 +1,28 @@
+package team
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/team"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"gorm.io/gorm"
+)
+
+func RegisterHandler(db *gorm.DB, cfg *config.AppConfig) *Handler {
+	repo := team.NewTeamRepo(db)
+	svc := team.NewTeamService(*repo, course.NewAccess(db), cfg)
+	return NewHandler(svc)
+}
+
+// RegisterRoutes expects router to already require authentication; authorize
+// builds the middleware that checks the current user's permissions.
+func (handler *Handler) RegisterRoutes(router *gin.RouterGroup, authorize func(...user.Permission) gin.HandlerFunc) {
+	teams := router.Group("/projects/:id/teams")
+	teams.GET("", authorize(user.PermViewProjects), handler.ListTeams)
+
+	teams = teams.Group("", authorize(user.PermEditProjects))
+	teams.POST("", handler.CreateTeam)
+	teams.PUT("/:teamID", handler.UpdateTeam)
+	teams.DELETE("/:teamID", handler.DeleteTeam)
+}
@@ -1,7 +1,10 @@
 package user
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sinasadeghi83/aut-grader/internal/api/user"
@@ -24,14 +27,121 @@ func (h *Handler) Login(c *gin.Context) {
 		rest.RespondError(c, http.StatusBadRequest, "Inavlid input", err)
 		return
 	}
-	user, token, err := h.Service.Login(lgIn.Username, lgIn.Password)
+	user, tokens, err := h.Service.Login(lgIn.Username, lgIn.Password)
 	if err != nil {
 		rest.RespondError(c, http.StatusUnauthorized, "Login failed", err)
 		return
 	}
 
+	respondTokens(c, user, tokens)
+}
+
+func (h *Handler) Refresh(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+	curUser, tokens, err := h.Service.Refresh(input.RefreshToken)
+	if err != nil {
+		rest.RespondError(c, http.StatusUnauthorized, "Refresh failed", err)
+		return
+	}
+
+	respondTokens(c, curUser, tokens)
+}
+
+func (h *Handler) Logout(c *gin.Context) {
+	var input LogoutInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	curToken := c.MustGet("curToken").(*user.TokenInfo)
+	if err := h.Service.Logout(curUser, curToken, input.RefreshToken); err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Logout failed", err)
+		return
+	}
+	rest.RespondNoContent(c)
+}
+
+// RevokeSessions logs another user out of every session, e.g. when their account is compromised.
+func (h *Handler) RevokeSessions(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid user id", err)
+		return
+	}
+	if err := h.Service.RevokeSessions(uint(userID)); err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+	rest.RespondNoContent(c)
+}
+
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	curUser := c.MustGet("curUser").(*user.User)
+	keys, err := h.Service.ListAPIKeys(curUser.ID)
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to list API keys", err)
+		return
+	}
+	rest.RespondOK(c, gin.H{
+		"keys": keys,
+	})
+}
+
+// CreateAPIKey answers with the key itself, which can't be retrieved again.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	var input APIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid input", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	key, raw, err := h.Service.CreateAPIKey(curUser, input.Name, input.Scopes)
+	if errors.Is(err, user.ErrInvalidScope) {
+		rest.RespondError(c, http.StatusUnprocessableEntity, "Invalid scopes", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to create API key", err)
+		return
+	}
+	rest.RespondCreated(c, gin.H{
+		"key":     key,
+		"api_key": raw,
+	})
+}
+
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		rest.RespondError(c, http.StatusBadRequest, "Invalid key id", err)
+		return
+	}
+
+	curUser := c.MustGet("curUser").(*user.User)
+	err = h.Service.RevokeAPIKey(curUser.ID, uint(keyID))
+	if errors.Is(err, user.ErrNotFound) {
+		rest.RespondError(c, http.StatusNotFound, "API key not found", err)
+		return
+	}
+	if err != nil {
+		rest.RespondError(c, http.StatusInternalServerError, "Failed to revoke API key", err)
+		return
+	}
+	rest.RespondNoContent(c)
+}
+
+func respondTokens(c *gin.Context, curUser *user.User, tokens *user.TokenPair) {
 	rest.RespondOK(c, gin.H{
-		"user":  user,
-		"token": token,
+		"user":          curUser,
+		"token":         tokens.AccessToken,
+		"expires_at":    tokens.ExpiresAt,
+		"refresh_token": tokens.RefreshToken,
 	})
 }
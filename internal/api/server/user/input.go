@@ -1,6 +1,22 @@
 package user
 
+import "github.com/sinasadeghi83/aut-grader/internal/api/user"
+
 type LoginInput struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutInput is optional; without a refresh token only the access token is revoked.
+type LogoutInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type APIKeyInput struct {
+	Name   string            `json:"name" binding:"required,max=60"`
+	Scopes []user.Permission `json:"scopes" binding:"required,min=1"`
+}
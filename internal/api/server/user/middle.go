@@ -5,9 +5,12 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
 	"github.com/sinasadeghi83/aut-grader/pkg/platform/rest"
 )
 
+// CheckAuth authenticates the request with either an access token
+// ("Authorization: Bearer <token>") or a personal API key ("Authorization: ApiKey <key>").
 func (h *Handler) CheckAuth(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 
@@ -18,7 +21,7 @@ func (h *Handler) CheckAuth(c *gin.Context) {
 	}
 
 	authToken := strings.Split(authHeader, " ")
-	if len(authToken) != 2 || authToken[0] != "Bearer" {
+	if len(authToken) != 2 || (authToken[0] != "Bearer" && authToken[0] != "ApiKey") {
 		rest.RespondError(c, http.StatusUnauthorized, "Invalid token format", nil)
 		c.Abort()
 		return
@@ -26,7 +29,19 @@ func (h *Handler) CheckAuth(c *gin.Context) {
 
 	tokenString := authToken[1]
 
-	user, err := h.Service.CheckToken(tokenString)
+	if authToken[0] == "ApiKey" {
+		user, err := h.Service.CheckAPIKey(tokenString)
+		if err != nil {
+			rest.RespondError(c, http.StatusUnauthorized, "Invalid API key", nil)
+			c.Abort()
+			return
+		}
+		c.Set("curUser", user)
+		c.Next()
+		return
+	}
+
+	user, token, err := h.Service.CheckToken(tokenString)
 	if err != nil {
 		rest.RespondError(c, http.StatusUnauthorized, "Invalid token", nil)
 		c.Abort()
@@ -34,5 +49,22 @@ func (h *Handler) CheckAuth(c *gin.Context) {
 	}
 
 	c.Set("curUser", user)
+	c.Set("curToken", token)
 	c.Next()
 }
+
+// Authorize returns a middleware that lets the request through only if the current
+// user has every one of perms. It must run after CheckAuth.
+func (h *Handler) Authorize(perms ...user.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		curUser := c.MustGet("curUser").(*user.User)
+		for _, perm := range perms {
+			if !curUser.Can(perm) {
+				rest.RespondError(c, http.StatusForbidden, "Permission denied", user.ErrForbidden)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -15,4 +15,22 @@ func RegisterHandler(db *gorm.DB, cfg *config.AppConfig) *Handler {
 
 func (handler *Handler) RegisterRoutes(router *gin.RouterGroup) {
 	router.POST("/login", handler.Login)
+	router.POST("/refresh", handler.Refresh)
+	router.POST("/logout", handler.CheckAuth, handler.Authorize(user.PermManageAccount), handler.Logout)
+}
+
+// RegisterAccountRoutes registers the routes for managing the current user's own
+// credentials. It expects router to already require authentication.
+func (handler *Handler) RegisterAccountRoutes(router *gin.RouterGroup) {
+	keys := router.Group("/keys", handler.Authorize(user.PermManageAccount))
+	keys.GET("", handler.ListAPIKeys)
+	keys.POST("", handler.CreateAPIKey)
+	keys.DELETE("/:id", handler.RevokeAPIKey)
+}
+
+// RegisterUserRoutes registers the routes for managing other users. It expects router
+// to already require authentication.
+func (handler *Handler) RegisterUserRoutes(router *gin.RouterGroup) {
+	users := router.Group("/users", handler.Authorize(user.PermManageUsers))
+	users.DELETE("/:id/sessions", handler.RevokeSessions)
 }
@@ -0,0 +1,10 @@
+package submission
+
+import "errors"
+
+var (
+	ErrProjectNotFound = errors.New("project not found")
+	ErrUploadsDisabled = errors.New("uploading submissions is disabled")
+	ErrTooLarge        = errors.New("submission is too large")
+	ErrUnsupported     = errors.New("submission must be a zip or tar archive or a Linux executable")
+)
@@ -0,0 +1,42 @@
+package submission
+
+import (
+	"time"
+
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/model"
+)
+
+// Kind tells how a submission is reached.
+type Kind string
+
+const (
+	KindURL     Kind = "url"     // deployed by the student at BaseUrl
+	KindArchive Kind = "archive" // uploaded source or build, started with the configured command
+	KindBinary  Kind = "binary"  // uploaded Linux executable
+)
+
+// Submission is a student's, or their team's, service for a project: either where they
+// deployed it or an upload the grader runs itself. Submitting again supersedes the
+// previous submission, which is kept as history; the one not superseded is active and
+// is what gets graded.
+type Submission struct {
+	model.Model
+	ProjectID    uint       `json:"project_id"`
+	UserID       uint       `json:"user_id"` // Who registered it
+	TeamID       *uint      `json:"team_id,omitempty"`
+	Kind         Kind       `json:"kind" gorm:"default:url"`
+	BaseUrl      string     `json:"base_url,omitempty" gorm:"type:varchar(2048)"`
+	Artifact     string     `json:"-"` // Path of the upload, relative to the submission dir
+	ArtifactName string     `json:"artifact_name,omitempty"`
+	SubmittedAt  time.Time  `json:"submitted_at"`
+	SupersededAt *time.Time `json:"superseded_at,omitempty"`
+}
+
+// Local tells whether the grader has to run the submission itself.
+func (sub *Submission) Local() bool {
+	return sub.Kind == KindArchive || sub.Kind == KindBinary
+}
+
+func (Submission) TableName() string {
+	return "submissions"
+}
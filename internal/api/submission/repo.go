@@ -0,0 +1,75 @@
+package submission
+
+import (
+	"time"
+
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"gorm.io/gorm"
+)
+
+type SubmissionRepo struct {
+	db *gorm.DB
+}
+
+func NewSubmissionRepo(db *gorm.DB) *SubmissionRepo {
+	return &SubmissionRepo{db}
+}
+
+func (repo SubmissionRepo) WithDB(db *gorm.DB) *SubmissionRepo {
+	repo.db = db
+	return &repo
+}
+
+// Transaction runs fn with a repo bound to a transaction.
+func (repo *SubmissionRepo) Transaction(fn func(tx *SubmissionRepo) error) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		return fn(repo.WithDB(tx))
+	})
+}
+
+func (repo *SubmissionRepo) FindProject(id uint) (*grader.Project, error) {
+	var proj grader.Project
+	result := repo.db.First(&proj, id)
+	return &proj, result.Error
+}
+
+// owned narrows a query to the submissions of a team, or of a user outside any team.
+func (repo *SubmissionRepo) owned(projID, userID uint, teamID *uint) *gorm.DB {
+	query := repo.db.Where("project_id = ?", projID)
+	if teamID != nil {
+		return query.Where("team_id = ?", *teamID)
+	}
+	return query.Where("user_id = ? AND team_id IS NULL", userID)
+}
+
+// History returns the submissions of an owner, newest first.
+func (repo *SubmissionRepo) History(projID, userID uint, teamID *uint) ([]Submission, error) {
+	subs := []Submission{}
+	result := repo.owned(projID, userID, teamID).Order("id DESC").Find(&subs)
+	return subs, result.Error
+}
+
+// FindActive returns the owner's active submission.
+func (repo *SubmissionRepo) FindActive(projID, userID uint, teamID *uint) (*Submission, error) {
+	var sub Submission
+	result := repo.owned(projID, userID, teamID).Where("superseded_at IS NULL").Order("id DESC").First(&sub)
+	return &sub, result.Error
+}
+
+// Supersede retires the owner's active submission, if any.
+func (repo *SubmissionRepo) Supersede(projID, userID uint, teamID *uint, at time.Time) error {
+	return repo.owned(projID, userID, teamID).
+		Model(&Submission{}).
+		Where("superseded_at IS NULL").
+		Update("superseded_at", at).Error
+}
+
+func (repo *SubmissionRepo) FindById(id uint) (*Submission, error) {
+	var sub Submission
+	result := repo.db.First(&sub, id)
+	return &sub, result.Error
+}
+
+func (repo *SubmissionRepo) Create(sub *Submission) error {
+	return repo.db.Create(sub).Error
+}
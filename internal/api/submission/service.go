@@ -0,0 +1,170 @@
+package submission
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/team"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"github.com/sinasadeghi83/aut-grader/pkg/runner"
+	"gorm.io/gorm"
+)
+
+// SubmissionService keeps track of where students have deployed their projects.
+// Members of a team share one submission.
+type SubmissionService struct {
+	repo   SubmissionRepo
+	teams  *team.TeamRepo
+	access *course.Access
+	cfg    *config.AppConfig
+}
+
+func NewSubmissionService(repo SubmissionRepo, teams *team.TeamRepo, access *course.Access, cfg *config.AppConfig) *SubmissionService {
+	return &SubmissionService{
+		repo:   repo,
+		teams:  teams,
+		access: access,
+		cfg:    cfg,
+	}
+}
+
+// Submit makes baseUrl the active submission of viewer, or of their team, for the project.
+func (s *SubmissionService) Submit(viewer *user.User, projID uint, baseUrl string) (*Submission, error) {
+	teamID, err := s.owner(viewer, projID)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &Submission{
+		ProjectID: projID,
+		UserID:    viewer.ID,
+		TeamID:    teamID,
+		Kind:      KindURL,
+		BaseUrl:   baseUrl,
+	}
+	return sub, s.save(sub)
+}
+
+// Upload stores an archive or a Linux executable for the grader to run, and makes it the
+// active submission of viewer, or of their team, for the project.
+func (s *SubmissionService) Upload(viewer *user.User, projID uint, name string, content io.Reader) (*Submission, error) {
+	if !s.cfg.RunnerEnabled {
+		return nil, ErrUploadsDisabled
+	}
+	teamID, err := s.owner(viewer, projID)
+	if err != nil {
+		return nil, err
+	}
+
+	artifact, err := s.store(projID, content)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(s.cfg.SubmissionDir, artifact)
+	kind := KindArchive
+	format, err := runner.Detect(path)
+	if errors.Is(err, runner.ErrUnsupportedFormat) {
+		os.Remove(path)
+		return nil, ErrUnsupported
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	if format == runner.FormatBinary {
+		kind = KindBinary
+	}
+
+	sub := &Submission{
+		ProjectID:    projID,
+		UserID:       viewer.ID,
+		TeamID:       teamID,
+		Kind:         kind,
+		Artifact:     artifact,
+		ArtifactName: filepath.Base(name),
+	}
+	if err := s.save(sub); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return sub, nil
+}
+
+// save makes sub the active submission of its owner.
+func (s *SubmissionService) save(sub *Submission) error {
+	sub.SubmittedAt = time.Now()
+	return s.repo.Transaction(func(tx *SubmissionRepo) error {
+		if err := tx.Supersede(sub.ProjectID, sub.UserID, sub.TeamID, sub.SubmittedAt); err != nil {
+			return err
+		}
+		return tx.Create(sub)
+	})
+}
+
+// store writes an upload under the submission dir and returns its path relative to it.
+// Uploads are never overwritten, since past submissions may still be graded.
+func (s *SubmissionService) store(projID uint, content io.Reader) (string, error) {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+	artifact := filepath.Join(fmt.Sprint(projID), hex.EncodeToString(name))
+	path := filepath.Join(s.cfg.SubmissionDir, artifact)
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(content, s.cfg.SubmissionMaxSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > s.cfg.SubmissionMaxSize {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return artifact, nil
+}
+
+// History returns the submissions of viewer, or of their team, newest first; the
+// active one, if any, comes first.
+func (s *SubmissionService) History(viewer *user.User, projID uint) ([]Submission, error) {
+	teamID, err := s.owner(viewer, projID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.History(projID, viewer.ID, teamID)
+}
+
+// owner checks that viewer may submit for the project and returns their team, if any.
+func (s *SubmissionService) owner(viewer *user.User, projID uint) (*uint, error) {
+	proj, err := s.repo.FindProject(projID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.access.IsMember(viewer, proj.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrProjectNotFound
+	}
+	return s.teams.FindTeamID(projID, viewer.ID)
+}
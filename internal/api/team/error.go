@@ -0,0 +1,10 @@
+package team
+
+import "errors"
+
+var ErrNotFound = errors.New("not found")
+var ErrForbidden = errors.New("forbidden")
+
+// ErrInvalidMembers is returned for members who can't join the team, e.g. because
+// they aren't enrolled in the project's course or are already in another team.
+var ErrInvalidMembers = errors.New("invalid members")
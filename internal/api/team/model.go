@@ -0,0 +1,37 @@
+package team
+
+import (
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/model"
+)
+
+// Team is a group of students working on a project together. Their grading runs are
+// attributed to the team and every member sees them.
+type Team struct {
+	model.Model
+	ProjectID uint     `json:"project_id"`
+	Name      string   `json:"name" gorm:"type:varchar(60)"`
+	Members   []Member `json:"members" gorm:"-"`
+}
+
+func (Team) TableName() string {
+	return "teams"
+}
+
+// Membership puts a user in a team. A user is in at most one team per project.
+type Membership struct {
+	TeamID    uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"primaryKey"`
+	ProjectID uint
+}
+
+func (Membership) TableName() string {
+	return "team_members"
+}
+
+// Member is a team member as listed with their team.
+type Member struct {
+	TeamID   uint   `json:"-"`
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
@@ -0,0 +1,158 @@
+package team
+
+import (
+	"slices"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/pkg/grader"
+	"gorm.io/gorm"
+)
+
+type TeamRepo struct {
+	db *gorm.DB
+}
+
+func NewTeamRepo(db *gorm.DB) *TeamRepo {
+	return &TeamRepo{db}
+}
+
+func (repo TeamRepo) WithDB(db *gorm.DB) *TeamRepo {
+	repo.db = db
+	return &repo
+}
+
+// Transaction runs fn with a repo bound to a transaction.
+func (repo *TeamRepo) Transaction(fn func(tx *TeamRepo) error) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		return fn(repo.WithDB(tx))
+	})
+}
+
+func (repo *TeamRepo) FindProject(id uint) (*grader.Project, error) {
+	var proj grader.Project
+	result := repo.db.First(&proj, id)
+	return &proj, result.Error
+}
+
+// List returns the teams of a project, or only the one userID is in if userID isn't 0.
+func (repo *TeamRepo) List(projID, userID uint) ([]Team, error) {
+	var teams []Team
+	query := repo.db.Where("project_id = ?", projID).Order("id")
+	if userID != 0 {
+		query = query.Where("id IN (?)", repo.db.Model(&Membership{}).Select("team_id").Where("user_id = ?", userID))
+	}
+	if err := query.Find(&teams).Error; err != nil {
+		return nil, err
+	}
+	return teams, repo.loadMembers(teams)
+}
+
+func (repo *TeamRepo) FindById(projID, teamID uint) (*Team, error) {
+	var team Team
+	if err := repo.db.Where("id = ? AND project_id = ?", teamID, projID).First(&team).Error; err != nil {
+		return nil, err
+	}
+	teams := []Team{team}
+	err := repo.loadMembers(teams)
+	return &teams[0], err
+}
+
+func (repo *TeamRepo) loadMembers(teams []Team) error {
+	if len(teams) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(teams))
+	byID := make(map[uint]*Team, len(teams))
+	for i := range teams {
+		ids[i] = teams[i].ID
+		byID[teams[i].ID] = &teams[i]
+		teams[i].Members = []Member{}
+	}
+
+	var members []Member
+	err := repo.db.Table("team_members").
+		Select("team_members.team_id, users.id AS user_id, users.username, users.name").
+		Joins("JOIN users ON users.id = team_members.user_id").
+		Where("team_members.team_id IN ?", ids).
+		Order("users.username").
+		Scan(&members).Error
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		byID[m.TeamID].Members = append(byID[m.TeamID].Members, m)
+	}
+	return nil
+}
+
+func (repo *TeamRepo) Save(team *Team) error {
+	return repo.db.Save(team).Error
+}
+
+// ReplaceMembers makes userIDs the only members of the team.
+func (repo *TeamRepo) ReplaceMembers(team *Team, userIDs []uint) error {
+	if err := repo.db.Where("team_id = ?", team.ID).Delete(&Membership{}).Error; err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+	rows := make([]Membership, len(userIDs))
+	for i, id := range userIDs {
+		rows[i] = Membership{TeamID: team.ID, UserID: id, ProjectID: team.ProjectID}
+	}
+	return repo.db.Create(&rows).Error
+}
+
+// Delete removes a team for good, so that the database detaches its results from it.
+func (repo *TeamRepo) Delete(team *Team) error {
+	return repo.db.Unscoped().Delete(team).Error
+}
+
+// TakenMembers returns which of userIDs are in a team of the project other than teamID.
+func (repo *TeamRepo) TakenMembers(projID, teamID uint, userIDs []uint) ([]uint, error) {
+	taken := []uint{}
+	result := repo.db.Model(&Membership{}).
+		Where("project_id = ? AND team_id <> ? AND user_id IN ?", projID, teamID, userIDs).
+		Order("user_id").
+		Pluck("user_id", &taken)
+	return taken, result.Error
+}
+
+// NotEnrolled returns which of userIDs aren't enrolled in the course.
+func (repo *TeamRepo) NotEnrolled(courseID uint, userIDs []uint) ([]uint, error) {
+	var enrolled []uint
+	err := repo.db.Model(&course.Enrollment{}).
+		Where("course_id = ? AND user_id IN ?", courseID, userIDs).
+		Pluck("user_id", &enrolled).Error
+	if err != nil {
+		return nil, err
+	}
+	missing := []uint{}
+	for _, id := range userIDs {
+		if !slices.Contains(enrolled, id) {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// FindTeamID returns the team userID is in for the project, or nil if there is none.
+func (repo *TeamRepo) FindTeamID(projID, userID uint) (*uint, error) {
+	var ids []uint
+	err := repo.db.Model(&Membership{}).
+		Where("project_id = ? AND user_id = ?", projID, userID).
+		Limit(1).
+		Pluck("team_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return &ids[0], nil
+}
+
+// IsMember reports whether userID is in the team.
+func (repo *TeamRepo) IsMember(teamID, userID uint) (bool, error) {
+	var count int64
+	err := repo.db.Model(&Membership{}).Where("team_id = ? AND user_id = ?", teamID, userID).Count(&count).Error
+	return count > 0, err
+}
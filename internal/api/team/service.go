@@ -0,0 +1,160 @@
+package team
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sinasadeghi83/aut-grader/internal/api/course"
+	"github.com/sinasadeghi83/aut-grader/internal/api/user"
+	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"gorm.io/gorm"
+)
+
+// TeamService manages the teams of projects. The staff of a project's course put
+// students in teams; students only see the team they're in.
+type TeamService struct {
+	repo   TeamRepo
+	access *course.Access
+	cfg    *config.AppConfig
+}
+
+func NewTeamService(repo TeamRepo, access *course.Access, cfg *config.AppConfig) *TeamService {
+	return &TeamService{
+		repo:   repo,
+		access: access,
+		cfg:    cfg,
+	}
+}
+
+// List returns the teams of a project: all of them to staff, and to everyone else
+// the team they're in, if any.
+func (s *TeamService) List(viewer *user.User, projID uint) ([]Team, error) {
+	courseID, err := s.courseOf(projID)
+	if err != nil {
+		return nil, err
+	}
+	staff, err := s.access.IsStaff(viewer, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if staff {
+		return s.repo.List(projID, 0)
+	}
+	member, err := s.access.IsMember(viewer, courseID)
+	if err != nil {
+		return nil, err
+	}
+	if !member {
+		return nil, ErrNotFound
+	}
+	return s.repo.List(projID, viewer.ID)
+}
+
+func (s *TeamService) Create(viewer *user.User, projID uint, name string, memberIDs []uint) (*Team, error) {
+	team := &Team{ProjectID: projID}
+	return team, s.save(viewer, team, name, memberIDs)
+}
+
+func (s *TeamService) Update(viewer *user.User, projID, teamID uint, name string, memberIDs []uint) (*Team, error) {
+	team, err := s.repo.FindById(projID, teamID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return team, s.save(viewer, team, name, memberIDs)
+}
+
+// Delete removes a team. Its past results stay, attributed to no team.
+func (s *TeamService) Delete(viewer *user.User, projID, teamID uint) error {
+	if err := s.checkStaff(viewer, projID); err != nil {
+		return err
+	}
+	team, err := s.repo.FindById(projID, teamID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(tx *TeamRepo) error {
+		return tx.Delete(team)
+	})
+}
+
+func (s *TeamService) save(viewer *user.User, team *Team, name string, memberIDs []uint) error {
+	if err := s.checkStaff(viewer, team.ProjectID); err != nil {
+		return err
+	}
+	courseID, err := s.courseOf(team.ProjectID)
+	if err != nil {
+		return err
+	}
+	memberIDs = slices.Compact(slices.Sorted(slices.Values(memberIDs)))
+
+	err = s.repo.Transaction(func(tx *TeamRepo) error {
+		missing, err := tx.NotEnrolled(courseID, memberIDs)
+		if err != nil {
+			return err
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%w: users %v aren't enrolled in the course", ErrInvalidMembers, missing)
+		}
+		taken, err := tx.TakenMembers(team.ProjectID, team.ID, memberIDs)
+		if err != nil {
+			return err
+		}
+		if len(taken) > 0 {
+			return fmt.Errorf("%w: users %v are already in another team", ErrInvalidMembers, taken)
+		}
+
+		team.Name = name
+		if err := tx.Save(team); err != nil {
+			return err
+		}
+		return tx.ReplaceMembers(team, memberIDs)
+	})
+	if err != nil {
+		return err
+	}
+
+	saved, err := s.repo.FindById(team.ProjectID, team.ID)
+	if err != nil {
+		return err
+	}
+	*team = *saved
+	return nil
+}
+
+func (s *TeamService) courseOf(projID uint) (uint, error) {
+	proj, err := s.repo.FindProject(projID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return proj.CourseID, nil
+}
+
+// checkStaff returns ErrNotFound if viewer can't see the project, and ErrForbidden
+// if they can but aren't on the staff of its course.
+func (s *TeamService) checkStaff(viewer *user.User, projID uint) error {
+	courseID, err := s.courseOf(projID)
+	if err != nil {
+		return err
+	}
+	if ok, err := s.access.IsStaff(viewer, courseID); err != nil || ok {
+		return err
+	}
+	ok, err := s.access.IsMember(viewer, courseID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return ErrForbidden
+}
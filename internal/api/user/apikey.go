@@ -0,0 +1,85 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix marks our keys, so that a leaked one is easy to recognize.
+const apiKeyPrefix = "agk_"
+
+// CreateAPIKey creates a key for the user, limited to scopes, and returns it along
+// with the key itself, which is not stored and can't be shown again.
+func (s *UserService) CreateAPIKey(user *User, name string, scopes []Permission) (*APIKey, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: a key needs at least one scope", ErrInvalidScope)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(scopablePermissions, scope) {
+			return nil, "", fmt.Errorf("%w: %q can't be given to a key", ErrInvalidScope, scope)
+		}
+		if !user.Role.Can(scope) {
+			return nil, "", fmt.Errorf("%w: role %s doesn't have %q", ErrInvalidScope, user.Role, scope)
+		}
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	raw := apiKeyPrefix + secret
+	key := &APIKey{
+		UserID:  user.ID,
+		Name:    name,
+		Prefix:  raw[:len(apiKeyPrefix)+6],
+		KeyHash: hashToken(raw),
+		Scopes:  slices.Compact(slices.Sorted(slices.Values(scopes))),
+	}
+	if err := s.repo.CreateAPIKey(key); err != nil {
+		return nil, "", err
+	}
+	return key, raw, nil
+}
+
+func (s *UserService) ListAPIKeys(userID uint) ([]APIKey, error) {
+	return s.repo.ListAPIKeys(userID)
+}
+
+func (s *UserService) RevokeAPIKey(userID, keyID uint) error {
+	ok, err := s.repo.DeleteAPIKey(userID, keyID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CheckAPIKey returns the user a key belongs to, limited to the key's scopes.
+func (s *UserService) CheckAPIKey(raw string) (*User, error) {
+	if !strings.HasPrefix(raw, apiKeyPrefix) {
+		return nil, ErrInvalidToken
+	}
+	key, err := s.repo.FindAPIKey(hashToken(raw))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.FindById(key.UserID)
+	if err != nil {
+		return nil, ErrInvalidCreds
+	}
+	if err := s.repo.TouchAPIKey(key.ID); err != nil {
+		return nil, err
+	}
+	user.scopes = key.Scopes
+	return user, nil
+}
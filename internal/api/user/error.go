@@ -7,3 +7,6 @@ var ErrInvalidSignature = errors.New("invalid signature")
 var ErrInvalidToken = errors.New("invalid token")
 var ErrExpiredToken = errors.New("expired token")
 var ErrNotFound = errors.New("not found")
+var ErrForbidden = errors.New("forbidden")
+var ErrRevokedToken = errors.New("revoked token")
+var ErrInvalidScope = errors.New("invalid scope")
@@ -1,13 +1,77 @@
 package user
 
-import "github.com/sinasadeghi83/aut-grader/pkg/platform/model"
+import (
+	"slices"
+	"time"
+
+	"github.com/sinasadeghi83/aut-grader/pkg/platform/model"
+)
 
 type User struct {
 	model.Model
 	Username string `json:"username" gorm:"unique;type:varchar(25)"`
-	Password string `json:"-" gorm:",type:varchar(25)"`
+	Name     string `json:"name" gorm:"type:varchar(100)"`
+	Email    string `json:"email" gorm:"type:varchar(255)"`
+	Password string `json:"-" gorm:"type:varchar(255)"`
+	Role     Role   `json:"role" gorm:"type:varchar(20);default:student"`
+	// TokenGeneration goes up when the user is logged out everywhere. Access tokens
+	// carry the generation they were issued in and are rejected once it's outdated.
+	TokenGeneration uint `json:"-"`
+	// scopes narrows what the user can do when they authenticated with an API key.
+	scopes []Permission
+}
+
+func (u *User) Can(perm Permission) bool {
+	if u.scopes != nil && !slices.Contains(u.scopes, perm) {
+		return false
+	}
+	return u.Role.Can(perm)
 }
 
 func (User) TableName() string {
 	return "users"
 }
+
+// RefreshToken is the server-side record of a refresh token. Only a hash of the
+// token is kept; a token is revoked once it has been used, so each one works once.
+type RefreshToken struct {
+	model.Model
+	UserID    uint
+	TokenHash string `gorm:"type:char(64)"`
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// TokenRevocation blocks the access token with TokenID before it expires. Revocations
+// are dropped once ExpiresAt passes, as the token has expired by then.
+type TokenRevocation struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint
+	TokenID   string `gorm:"type:varchar(64)"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func (TokenRevocation) TableName() string {
+	return "token_revocations"
+}
+
+// APIKey is a named, long-lived credential for scripts such as CI pipelines. Only a
+// hash of the key is stored; Prefix is kept to tell keys apart when listing them.
+type APIKey struct {
+	model.Model
+	UserID     uint         `json:"-"`
+	Name       string       `json:"name" gorm:"type:varchar(60)"`
+	Prefix     string       `json:"prefix" gorm:"type:varchar(16)"`
+	KeyHash    string       `json:"-" gorm:"type:char(64)"`
+	Scopes     []Permission `json:"scopes" gorm:"serializer:json"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
@@ -1,6 +1,8 @@
 package user
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -33,3 +35,97 @@ func (repo *UserRepo) FindByUsername(username string) (*User, error) {
 	result := repo.db.Where("username = ?", username).First(&user)
 	return &user, result.Error
 }
+
+func (repo *UserRepo) UpdatePassword(id uint, hash string) error {
+	return repo.db.Model(&User{}).Where("id = ?", id).Update("password", hash).Error
+}
+
+func (repo *UserRepo) CreateRefreshToken(token *RefreshToken) error {
+	return repo.db.Create(token).Error
+}
+
+func (repo *UserRepo) FindRefreshToken(hash string) (*RefreshToken, error) {
+	var token RefreshToken
+	result := repo.db.Where("token_hash = ?", hash).First(&token)
+	return &token, result.Error
+}
+
+// UseRefreshToken revokes a refresh token, reporting false if it already was.
+func (repo *UserRepo) UseRefreshToken(id uint) (bool, error) {
+	result := repo.db.Model(&RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+func (repo *UserRepo) RevokeRefreshToken(userID uint, hash string) error {
+	return repo.db.Model(&RefreshToken{}).
+		Where("user_id = ? AND token_hash = ? AND revoked_at IS NULL", userID, hash).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (repo *UserRepo) RevokeRefreshTokens(userID uint) error {
+	return repo.db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// Revoke records a revocation, clearing out the ones that have expired.
+func (repo *UserRepo) Revoke(rev *TokenRevocation) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&TokenRevocation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(rev).Error
+	})
+}
+
+// IsRevoked reports whether the access token tokenID of the user was revoked.
+func (repo *UserRepo) IsRevoked(userID uint, tokenID string) (bool, error) {
+	var count int64
+	err := repo.db.Model(&TokenRevocation{}).
+		Where("user_id = ? AND token_id = ? AND expires_at >= ?", userID, tokenID, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// BumpTokenGeneration rejects every access token issued to the user so far.
+func (repo *UserRepo) BumpTokenGeneration(userID uint) error {
+	return repo.db.Model(&User{}).Where("id = ?", userID).
+		UpdateColumn("token_generation", gorm.Expr("token_generation + 1")).Error
+}
+
+func (repo *UserRepo) CreateAPIKey(key *APIKey) error {
+	return repo.db.Create(key).Error
+}
+
+func (repo *UserRepo) ListAPIKeys(userID uint) ([]APIKey, error) {
+	var keys []APIKey
+	result := repo.db.Where("user_id = ?", userID).Order("id").Find(&keys)
+	return keys, result.Error
+}
+
+func (repo *UserRepo) FindAPIKey(hash string) (*APIKey, error) {
+	var key APIKey
+	result := repo.db.Where("key_hash = ?", hash).First(&key)
+	return &key, result.Error
+}
+
+// DeleteAPIKey revokes a key of the user, reporting false if there was none.
+func (repo *UserRepo) DeleteAPIKey(userID, keyID uint) (bool, error) {
+	result := repo.db.Where("id = ? AND user_id = ?", keyID, userID).Delete(&APIKey{})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (repo *UserRepo) DeleteAPIKeys(userID uint) error {
+	return repo.db.Where("user_id = ?", userID).Delete(&APIKey{}).Error
+}
+
+// TouchAPIKey records that a key was used. It writes at most once a minute per key,
+// since a CI pipeline may use its key for many requests in a row.
+func (repo *UserRepo) TouchAPIKey(id uint) error {
+	now := time.Now()
+	return repo.db.Model(&APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-time.Minute)).
+		UpdateColumn("last_used_at", now).Error
+}
@@ -0,0 +1,54 @@
+package user
+
+import "slices"
+
+type Role string
+
+const (
+	RoleAdmin      Role = "admin"
+	RoleInstructor Role = "instructor"
+	RoleTA         Role = "ta"
+	RoleStudent    Role = "student"
+)
+
+// Permission is something a route may require of the current user.
+type Permission string
+
+const (
+	PermViewProjects Permission = "projects:view"
+	PermEditProjects Permission = "projects:edit"
+	PermGrade        Permission = "grading:run"
+	// PermAllResults allows viewing and cancelling results of other users in the
+	// courses one is staff of; without it users only see their own.
+	PermAllResults  Permission = "results:all"
+	PermManageUsers Permission = "users:manage"
+	// PermManageCourses allows creating courses and importing their rosters.
+	PermManageCourses Permission = "courses:manage"
+	// PermAllCourses reaches every course as its staff, enrolled or not.
+	PermAllCourses Permission = "courses:all"
+	// PermManageAccount covers a user's own sessions and API keys. API keys can't
+	// be scoped to it, so only an interactive login can manage them.
+	PermManageAccount Permission = "account:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin:      {PermManageAccount, PermViewProjects, PermEditProjects, PermGrade, PermAllResults, PermManageUsers, PermManageCourses, PermAllCourses},
+	RoleInstructor: {PermManageAccount, PermViewProjects, PermEditProjects, PermGrade, PermAllResults, PermManageCourses},
+	RoleTA:         {PermManageAccount, PermViewProjects, PermGrade, PermAllResults},
+	RoleStudent:    {PermManageAccount, PermViewProjects, PermGrade},
+}
+
+// scopablePermissions are the permissions an API key may be given.
+var scopablePermissions = []Permission{
+	PermViewProjects, PermEditProjects, PermGrade, PermAllResults, PermManageUsers, PermManageCourses, PermAllCourses,
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role grants perm. Unknown roles grant nothing.
+func (r Role) Can(perm Permission) bool {
+	return slices.Contains(rolePermissions[r], perm)
+}
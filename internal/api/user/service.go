@@ -1,10 +1,14 @@
 package user
 
 import (
+	"crypto/subtle"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/sinasadeghi83/aut-grader/pkg/config"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
@@ -19,27 +23,77 @@ func NewUserService(repo UserRepo, cfg *config.AppConfig) *UserService {
 	}
 }
 
-func (s *UserService) Login(username, password string) (*User, string, error) {
+// Create stores a new user with a hashed password.
+func (s *UserService) Create(username, password string, role Role) (*User, error) {
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.Create(User{Username: username, Password: hash, Role: role})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *UserService) Login(username, password string) (*User, *TokenPair, error) {
 	user, err := s.repo.FindByUsername(username)
 	if err != nil || !s.CheckPassword(password, user.Password) {
-		return nil, "", ErrInvalidCreds
+		return nil, nil, ErrInvalidCreds
 	}
 
-	generateToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"id":  user.ID,
-		"exp": time.Now().Add(time.Hour * 24).Unix(),
-	})
+	// Passwords stored before hashing was introduced are upgraded once we know them.
+	if !isHash(user.Password) {
+		if err := s.rehash(user, password); err != nil {
+			log.Printf("failed to rehash the password of user %d: %v", user.ID, err)
+		}
+	}
 
-	token, err := generateToken.SignedString([]byte(s.cfg.SecretKey))
+	tokens, err := s.issueTokens(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
 
-	return user, token, err
+func (s *UserService) HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
 }
 
+// CheckPassword compares password with a stored bcrypt hash, or with a legacy
+// plaintext password that hasn't been rehashed yet.
 func (s *UserService) CheckPassword(password, hash string) bool {
-	return password == hash
+	if isHash(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(password), []byte(hash)) == 1
 }
 
-func (s *UserService) CheckToken(tokenStr string) (*User, error) {
+func (s *UserService) rehash(user *User, password string) error {
+	hash, err := s.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdatePassword(user.ID, hash); err != nil {
+		return err
+	}
+	user.Password = hash
+	return nil
+}
+
+// isHash tells bcrypt hashes apart from legacy plaintext passwords, which the old
+// varchar(25) column kept too short to look like one.
+func isHash(password string) bool {
+	_, err := bcrypt.Cost([]byte(password))
+	return err == nil
+}
+
+// CheckToken validates an access token and returns its user, unless the token was revoked.
+func (s *UserService) CheckToken(tokenStr string) (*User, *TokenInfo, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, ErrInvalidSignature
@@ -47,26 +101,50 @@ func (s *UserService) CheckToken(tokenStr string) (*User, error) {
 		return []byte(s.cfg.SecretKey), nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if !token.Valid {
-		return nil, ErrInvalidToken
+		return nil, nil, ErrInvalidToken
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return nil, ErrInvalidToken
+		return nil, nil, ErrInvalidToken
 	}
 
-	if float64(time.Now().Unix()) > claims["exp"].(float64) {
-		return nil, ErrExpiredToken
+	exp, _ := claims["exp"].(float64)
+	if float64(time.Now().Unix()) > exp {
+		return nil, nil, ErrExpiredToken
 	}
 
-	user, err := s.repo.FindById(uint(claims["id"].(float64)))
+	id, _ := claims["id"].(float64)
+	user, err := s.repo.FindById(uint(id))
 	if err != nil {
-		return nil, ErrInvalidCreds
+		return nil, nil, ErrInvalidCreds
+	}
+
+	// A token issued before the user's role changed no longer grants anything.
+	if role, _ := claims["role"].(string); Role(role) != user.Role {
+		return nil, nil, ErrInvalidToken
+	}
+
+	info := &TokenInfo{ExpiresAt: time.Unix(int64(exp), 0)}
+	info.ID, _ = claims["jti"].(string)
+	if info.ID == "" {
+		return nil, nil, ErrInvalidToken
+	}
+	// A token issued before the user was logged out everywhere is from an older generation.
+	if gen, _ := claims["gen"].(float64); uint(gen) != user.TokenGeneration {
+		return nil, nil, ErrRevokedToken
+	}
+	revoked, err := s.repo.IsRevoked(user.ID, info.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, ErrRevokedToken
 	}
 
-	return user, nil
+	return user, info, nil
 }
@@ -0,0 +1,140 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"gorm.io/gorm"
+)
+
+// TokenPair is what a user gets on login and on every refresh: a short-lived access
+// token and the refresh token to exchange for the next pair.
+type TokenPair struct {
+	AccessToken  string    `json:"token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+}
+
+// TokenInfo describes the access token a request was authenticated with.
+type TokenInfo struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+func (s *UserService) issueTokens(user *User) (*TokenPair, error) {
+	now := time.Now()
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	pair := &TokenPair{ExpiresAt: now.Add(s.cfg.AccessTokenTTL)}
+
+	generateToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":   user.ID,
+		"role": user.Role,
+		"jti":  jti,
+		"gen":  user.TokenGeneration,
+		"iat":  now.Unix(),
+		"exp":  pair.ExpiresAt.Unix(),
+	})
+	if pair.AccessToken, err = generateToken.SignedString([]byte(s.cfg.SecretKey)); err != nil {
+		return nil, err
+	}
+
+	if pair.RefreshToken, err = randomToken(32); err != nil {
+		return nil, err
+	}
+	err = s.repo.CreateRefreshToken(&RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(pair.RefreshToken),
+		ExpiresAt: now.Add(s.cfg.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new pair and retires the old one. A refresh
+// token used twice means it leaked, so every session of its user is revoked.
+func (s *UserService) Refresh(refreshToken string) (*User, *TokenPair, error) {
+	stored, err := s.repo.FindRefreshToken(hashToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, nil, ErrExpiredToken
+	}
+
+	used, err := s.repo.UseRefreshToken(stored.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !used {
+		if err := s.RevokeSessions(stored.UserID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRevokedToken
+	}
+
+	user, err := s.repo.FindById(stored.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidCreds
+	}
+	tokens, err := s.issueTokens(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// Logout revokes the access token the user authenticated with and, if given, the
+// refresh token that came with it.
+func (s *UserService) Logout(user *User, token *TokenInfo, refreshToken string) error {
+	if refreshToken != "" {
+		if err := s.repo.RevokeRefreshToken(user.ID, hashToken(refreshToken)); err != nil {
+			return err
+		}
+	}
+	return s.repo.Revoke(&TokenRevocation{
+		UserID:    user.ID,
+		TokenID:   token.ID,
+		ExpiresAt: token.ExpiresAt,
+	})
+}
+
+// RevokeSessions logs a user out everywhere: their refresh tokens and API keys stop
+// working and access tokens issued so far are rejected until they expire.
+func (s *UserService) RevokeSessions(userID uint) error {
+	if err := s.repo.RevokeRefreshTokens(userID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAPIKeys(userID); err != nil {
+		return err
+	}
+	return s.repo.BumpTokenGeneration(userID)
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is how refresh tokens are stored. They are random enough that a fast
+// hash is as good as a slow one.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
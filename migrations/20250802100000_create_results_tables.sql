@@ -0,0 +1,94 @@
+-- +goose Up
+-- +goose StatementBegin
+create table project_results(
+    id bigint unsigned primary key auto_increment,
+    project_id bigint unsigned not null,
+    project_name varchar(60) not null,
+    user_id bigint unsigned not null,
+    status varchar(20) not null,
+    message TEXT null,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (user_id) references users(id) on delete cascade,
+
+    index idx_project_results_user_project (user_id, project_id),
+    index idx_project_results_status (status)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create table section_results(
+    id bigint unsigned primary key auto_increment,
+    section_id bigint unsigned not null,
+    section_name varchar(60) not null,
+    project_result_id bigint unsigned not null,
+    status varchar(20) not null,
+    message TEXT null,
+    total_scenarios int unsigned not null default 0,
+    passed_scenarios int unsigned not null default 0,
+    score double not null default 0,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (project_result_id) references project_results(id) on delete cascade,
+
+    index idx_section_results_status (status)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create table scenario_results(
+    id bigint unsigned primary key auto_increment,
+    scenario_id bigint unsigned not null,
+    scenario_name varchar(60) not null,
+    section_result_id bigint unsigned not null,
+    status varchar(20) not null,
+    message TEXT null,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (section_result_id) references section_results(id) on delete cascade,
+
+    index idx_scenario_results_status (status)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create table test_results(
+    id bigint unsigned primary key auto_increment,
+    test_id bigint unsigned not null,
+    test_name varchar(60) not null,
+    scenario_result_id bigint unsigned not null,
+    status varchar(20) not null,
+    message TEXT null,
+    actual_status_code int unsigned not null default 0,
+    expected_status_code int unsigned not null default 0,
+    actual_response_body MEDIUMTEXT null,
+    expected_response_body TEXT null,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (scenario_result_id) references scenario_results(id) on delete cascade,
+
+    index idx_test_results_status (status)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table test_results;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table scenario_results;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table section_results;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table project_results;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+create table grade_jobs(
+    id bigint unsigned primary key auto_increment,
+    project_id bigint unsigned not null,
+    user_id bigint unsigned not null,
+    base_url varchar(2048) not null,
+    project_result_id bigint unsigned not null,
+    status varchar(20) not null,
+    error TEXT null,
+    started_at datetime null,
+    finished_at datetime null,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (project_id) references projects(id) on delete cascade,
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (project_result_id) references project_results(id) on delete cascade,
+
+    index idx_grade_jobs_status (status, id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table grade_jobs;
+-- +goose StatementEnd
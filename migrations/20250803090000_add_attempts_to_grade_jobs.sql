@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table grade_jobs
+    add column attempts int unsigned not null default 0 after status;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table grade_jobs
+    drop column attempts;
+-- +goose StatementEnd
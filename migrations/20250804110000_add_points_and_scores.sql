@@ -0,0 +1,66 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table sections
+    add column points decimal(8,2) not null default 1 after name;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table scenarios
+    add column points decimal(8,2) not null default 1 after name;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table tests
+    add column points decimal(8,2) not null default 1 after name;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+alter table project_results
+    add column score double not null default 0 after message,
+    add column max_score double not null default 0 after score;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table section_results
+    add column max_score double not null default 0 after score;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table scenario_results
+    add column score double not null default 0 after message,
+    add column max_score double not null default 0 after score;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table test_results
+    add column score double not null default 0 after message,
+    add column max_score double not null default 0 after score;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table test_results
+    drop column max_score,
+    drop column score;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table scenario_results
+    drop column max_score,
+    drop column score;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table section_results
+    drop column max_score;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table project_results
+    drop column max_score,
+    drop column score;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table tests
+    drop column points;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table scenarios
+    drop column points;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table sections
+    drop column points;
+-- +goose StatementEnd
@@ -0,0 +1,186 @@
+-- +goose Up
+-- +goose StatementBegin
+create table section_dependencies(
+    section_id bigint unsigned not null,
+    depends_on_id bigint unsigned not null,
+
+    primary key (section_id, depends_on_id),
+    foreign key (section_id) references sections(id) on delete cascade,
+    foreign key (depends_on_id) references sections(id) on delete cascade,
+
+    index idx_section_dependencies_depends_on (depends_on_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create table scenario_dependencies(
+    scenario_id bigint unsigned not null,
+    depends_on_id bigint unsigned not null,
+
+    primary key (scenario_id, depends_on_id),
+    foreign key (scenario_id) references scenarios(id) on delete cascade,
+    foreign key (depends_on_id) references scenarios(id) on delete cascade,
+
+    index idx_scenario_dependencies_depends_on (depends_on_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create table test_dependencies(
+    test_id bigint unsigned not null,
+    depends_on_id bigint unsigned not null,
+
+    primary key (test_id, depends_on_id),
+    foreign key (test_id) references tests(id) on delete cascade,
+    foreign key (depends_on_id) references tests(id) on delete cascade,
+
+    index idx_test_dependencies_depends_on (depends_on_id)
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+insert into section_dependencies (section_id, depends_on_id)
+select id, depends_on_id from sections where depends_on_id is not null;
+-- +goose StatementEnd
+-- +goose StatementBegin
+insert into scenario_dependencies (scenario_id, depends_on_id)
+select id, depends_on_id from scenarios where depends_on_id is not null;
+-- +goose StatementEnd
+-- +goose StatementBegin
+insert into test_dependencies (test_id, depends_on_id)
+select id, depends_on_id from tests where depends_on_id is not null;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+drop trigger before_section_insert;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop trigger before_section_update;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop trigger before_scenario_insert;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop trigger before_scenario_update;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop trigger before_test_insert;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop trigger before_test_update;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+alter table sections
+    drop foreign key sections_ibfk_1,
+    drop index idx_sections_depends_on,
+    drop column depends_on_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table scenarios
+    drop foreign key scenarios_ibfk_1,
+    drop index idx_scenario_depends_on,
+    drop column depends_on_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table tests
+    drop foreign key tests_ibfk_2,
+    drop index idx_tests_depends_on,
+    drop column depends_on_id;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER before_section_dependency_insert
+BEFORE INSERT ON section_dependencies
+FOR EACH ROW
+BEGIN
+    IF NEW.section_id = NEW.depends_on_id THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'a section cannot depend on itself';
+    END IF;
+    IF (SELECT project_id FROM sections WHERE id = NEW.depends_on_id) != (SELECT project_id FROM sections WHERE id = NEW.section_id) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'depends_on section must be in the same project';
+    END IF;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER before_scenario_dependency_insert
+BEFORE INSERT ON scenario_dependencies
+FOR EACH ROW
+BEGIN
+    IF NEW.scenario_id = NEW.depends_on_id THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'a scenario cannot depend on itself';
+    END IF;
+    IF (SELECT section_id FROM scenarios WHERE id = NEW.depends_on_id) != (SELECT section_id FROM scenarios WHERE id = NEW.scenario_id) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'depends_on scenario must be in the same section';
+    END IF;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER before_test_dependency_insert
+BEFORE INSERT ON test_dependencies
+FOR EACH ROW
+BEGIN
+    IF NEW.test_id = NEW.depends_on_id THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'a test cannot depend on itself';
+    END IF;
+    IF (SELECT scenario_id FROM tests WHERE id = NEW.depends_on_id) != (SELECT scenario_id FROM tests WHERE id = NEW.test_id) THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'depends_on test must be in the same scenario';
+    END IF;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop trigger before_test_dependency_insert;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop trigger before_scenario_dependency_insert;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop trigger before_section_dependency_insert;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+alter table sections
+    add column depends_on_id bigint unsigned null after name,
+    add index idx_sections_depends_on (depends_on_id),
+    add foreign key (depends_on_id) references sections(id) on delete cascade;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table scenarios
+    add column depends_on_id bigint unsigned null after name,
+    add index idx_scenario_depends_on (depends_on_id),
+    add foreign key (depends_on_id) references scenarios(id) on delete cascade;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table tests
+    add column depends_on_id bigint unsigned null after res_body,
+    add index idx_tests_depends_on (depends_on_id),
+    add foreign key (depends_on_id) references tests(id) on delete cascade;
+-- +goose StatementEnd
+
+-- Only one dependency per node survives the way down.
+-- +goose StatementBegin
+update sections s join (select section_id, min(depends_on_id) as dep from section_dependencies group by section_id) d
+    on d.section_id = s.id set s.depends_on_id = d.dep;
+-- +goose StatementEnd
+-- +goose StatementBegin
+update scenarios s join (select scenario_id, min(depends_on_id) as dep from scenario_dependencies group by scenario_id) d
+    on d.scenario_id = s.id set s.depends_on_id = d.dep;
+-- +goose StatementEnd
+-- +goose StatementBegin
+update tests t join (select test_id, min(depends_on_id) as dep from test_dependencies group by test_id) d
+    on d.test_id = t.id set t.depends_on_id = d.dep;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+drop table test_dependencies;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table scenario_dependencies;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table section_dependencies;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Existing users become students; promote staff afterwards, e.g.
+-- update users set role = 'admin' where username = '...';
+alter table users
+    add column role varchar(20) not null default 'student' after password,
+    add constraint chk_users_role check (role in ('admin', 'instructor', 'ta', 'student'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table users
+    drop check chk_users_role,
+    drop column role;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Existing plaintext passwords are rehashed on the user's next login.
+alter table users
+    modify column password varchar(255) not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Hashes don't fit the old column, so they can't be kept on the way down.
+update users set password = '' where char_length(password) > 25;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table users
+    modify column password varchar(25) not null;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column name varchar(100) not null default '' after username,
+    add column email varchar(255) not null default '' after name;
+-- +goose StatementEnd
+-- +goose StatementBegin
+create table courses(
+    id bigint unsigned primary key auto_increment,
+    name varchar(100) not null,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+create table enrollments(
+    course_id bigint unsigned not null,
+    user_id bigint unsigned not null,
+    created_at datetime not null default current_timestamp,
+
+    primary key (course_id, user_id),
+    foreign key (course_id) references courses(id) on delete cascade,
+    foreign key (user_id) references users(id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table enrollments;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table courses;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table users
+    drop column email,
+    drop column name;
+-- +goose StatementEnd
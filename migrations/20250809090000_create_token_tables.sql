@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+create table refresh_tokens(
+    id bigint unsigned primary key auto_increment,
+    user_id bigint unsigned not null,
+    token_hash char(64) not null,
+    expires_at datetime not null,
+    revoked_at datetime null,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (user_id) references users(id) on delete cascade,
+    unique index idx_refresh_tokens_hash (token_hash)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+create table token_revocations(
+    id bigint unsigned primary key auto_increment,
+    user_id bigint unsigned not null,
+    token_id varchar(64) null,
+    expires_at datetime not null,
+    created_at datetime not null default current_timestamp,
+
+    foreign key (user_id) references users(id) on delete cascade,
+    index idx_token_revocations_user (user_id, expires_at),
+    index idx_token_revocations_expiry (expires_at)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table token_revocations;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table refresh_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+create table api_keys(
+    id bigint unsigned primary key auto_increment,
+    user_id bigint unsigned not null,
+    name varchar(60) not null,
+    prefix varchar(16) not null,
+    key_hash char(64) not null,
+    scopes json not null,
+    last_used_at datetime null,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (user_id) references users(id) on delete cascade,
+    unique index idx_api_keys_hash (key_hash),
+    index idx_api_keys_user (user_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table api_keys;
+-- +goose StatementEnd
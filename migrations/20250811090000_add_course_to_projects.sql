@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table enrollments
+    add column role varchar(20) not null default 'student' after user_id,
+    add constraint chk_enrollments_role check (role in ('student', 'staff'));
+-- +goose StatementEnd
+
+-- Projects created before courses existed go to a course of their own, which every
+-- existing user is enrolled in, so that nobody loses access to what they could see.
+-- +goose StatementBegin
+insert into courses (name)
+select 'Default course' from dual where exists (select 1 from projects);
+-- +goose StatementEnd
+-- +goose StatementBegin
+insert ignore into enrollments (course_id, user_id, role)
+select c.id, u.id, if(u.role = 'student', 'student', 'staff')
+from courses c
+join users u on u.deleted_at is null
+where c.id = (select max(id) from courses where name = 'Default course')
+  and exists (select 1 from projects);
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table projects
+    add column course_id bigint unsigned null after due;
+-- +goose StatementEnd
+-- +goose StatementBegin
+update projects
+set course_id = (select max(id) from courses where name = 'Default course');
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table projects
+    modify column course_id bigint unsigned not null,
+    add constraint fk_projects_course foreign key (course_id) references courses(id) on delete cascade;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table projects
+    drop foreign key fk_projects_course,
+    drop column course_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table enrollments
+    drop check chk_enrollments_role,
+    drop column role;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+create table teams(
+    id bigint unsigned primary key auto_increment,
+    project_id bigint unsigned not null,
+    name varchar(60) not null,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (project_id) references projects(id) on delete cascade
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+create table team_members(
+    team_id bigint unsigned not null,
+    user_id bigint unsigned not null,
+    project_id bigint unsigned not null,
+
+    primary key (team_id, user_id),
+    -- A user is in at most one team per project.
+    unique index idx_team_members_project_user (project_id, user_id),
+    foreign key (team_id) references teams(id) on delete cascade,
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (project_id) references projects(id) on delete cascade
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table project_results
+    add column team_id bigint unsigned null after user_id,
+    add constraint fk_project_results_team foreign key (team_id) references teams(id) on delete set null;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table grade_jobs
+    add column team_id bigint unsigned null after user_id,
+    add constraint fk_grade_jobs_team foreign key (team_id) references teams(id) on delete set null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table grade_jobs
+    drop foreign key fk_grade_jobs_team,
+    drop column team_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table project_results
+    drop foreign key fk_project_results_team,
+    drop column team_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table team_members;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table teams;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+create table submissions(
+    id bigint unsigned primary key auto_increment,
+    project_id bigint unsigned not null,
+    user_id bigint unsigned not null,
+    team_id bigint unsigned null,
+    base_url varchar(2048) not null,
+    submitted_at datetime not null,
+    superseded_at datetime null,
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (project_id) references projects(id) on delete cascade,
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (team_id) references teams(id) on delete cascade,
+
+    index idx_submissions_user (project_id, user_id, team_id),
+    index idx_submissions_team (project_id, team_id)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table grade_jobs
+    add column submission_id bigint unsigned null after team_id,
+    add constraint fk_grade_jobs_submission foreign key (submission_id) references submissions(id) on delete set null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table grade_jobs
+    drop foreign key fk_grade_jobs_submission,
+    drop column submission_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table submissions;
+-- +goose StatementEnd
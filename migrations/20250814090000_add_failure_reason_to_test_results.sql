@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table test_results
+    add column reason varchar(30) not null default '' after status;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table test_results
+    drop column reason;
+-- +goose StatementEnd
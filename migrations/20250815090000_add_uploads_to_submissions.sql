@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table submissions
+    add column kind varchar(20) not null default 'url' after team_id,
+    modify column base_url varchar(2048) not null default '',
+    add column artifact varchar(255) not null default '' after base_url,
+    add column artifact_name varchar(255) not null default '' after artifact;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table project_results
+    add column output MEDIUMTEXT null after message;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table project_results
+    drop column output;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table submissions
+    drop column artifact_name,
+    drop column artifact,
+    modify column base_url varchar(2048) not null,
+    drop column kind;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+create table tassertions(
+    id bigint unsigned primary key auto_increment,
+    expr varchar(300) not null,
+    test_id bigint unsigned not null,
+
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (test_id) references tests(id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create table assertion_results(
+    id bigint unsigned primary key auto_increment,
+    test_result_id bigint unsigned not null,
+    expr varchar(300) not null,
+    passed boolean not null default false,
+    actual TEXT null,
+    message TEXT null,
+
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (test_result_id) references test_results(id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table assertion_results;
+-- +goose StatementEnd
+-- +goose StatementBegin
+drop table tassertions;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table tests
+    add column object_mode varchar(10) not null default '',
+    add column array_mode varchar(10) not null default '';
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+create table tcomparisons(
+    id bigint unsigned primary key auto_increment,
+    path varchar(300) not null,
+    object_mode varchar(10) not null default '',
+    array_mode varchar(10) not null default '',
+    test_id bigint unsigned not null,
+
+    created_at datetime not null default current_timestamp,
+    updated_at datetime not null default current_timestamp on update current_timestamp,
+    deleted_at datetime default null,
+
+    foreign key (test_id) references tests(id) on delete cascade
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop table tcomparisons;
+-- +goose StatementEnd
+-- +goose StatementBegin
+alter table tests
+    drop column object_mode,
+    drop column array_mode;
+-- +goose StatementEnd
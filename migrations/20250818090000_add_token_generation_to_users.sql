@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table users
+    add column token_generation int unsigned not null default 0;
+-- +goose StatementEnd
+
+-- Revocations of all of a user's tokens become a new generation.
+-- +goose StatementBegin
+update users set token_generation = 1
+where id in (select user_id from token_revocations where token_id is null and expires_at >= now());
+-- +goose StatementEnd
+-- +goose StatementBegin
+delete from token_revocations where token_id is null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table users
+    drop column token_generation;
+-- +goose StatementEnd
@@ -2,16 +2,53 @@ package config
 
 import (
 	"log"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // AppConfig holds the application configuration.
 type AppConfig struct {
-	ServerPort string
-	DbURL      string
-	SecretKey  string
+	ServerPort    string
+	DbURL         string
+	SecretKey     string
+	GraderWorkers int
+	// GraderConcurrency is how many scenarios of one grading run may hit the student's server at once.
+	GraderConcurrency int
+	// GraderTestTimeout bounds a single test's request, GraderRunTimeout a whole grading run.
+	GraderTestTimeout time.Duration
+	GraderRunTimeout  time.Duration
+	// GraderAllowedTargets may be graded even if they're on a private network;
+	// GraderBlockedTargets never are. Loopback, private and link-local addresses
+	// are blocked unless allowed.
+	GraderAllowedTargets Targets
+	GraderBlockedTargets Targets
+	// SubmissionDir is where uploaded submissions are stored, and SubmissionMaxSize
+	// how large an upload may be, in bytes.
+	SubmissionDir     string
+	SubmissionMaxSize int64
+	// RunnerEnabled lets students upload their service for the grader to run on this
+	// machine instead of hosting it themselves. The Runner* settings say how it's run.
+	RunnerEnabled      bool
+	RunnerCommand      string
+	RunnerHealthPath   string
+	RunnerStartTimeout time.Duration
+	RunnerWallTime     time.Duration
+	RunnerCPUTime      time.Duration
+	RunnerMemory       int64
+	RunnerScratchDir   string
+	// RunnerUID and RunnerGID are the unprivileged user and group submissions run as.
+	// They're required with RunnerEnabled, which needs the server to run as root.
+	RunnerUID uint32
+	RunnerGID uint32
+	// AccessTokenTTL is how long an access token lasts; RefreshTokenTTL how long a
+	// session may go without being refreshed.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 }
 
 // LoadConfig loads configuration from environment variables or .env file.
@@ -36,9 +73,119 @@ func LoadConfig() *AppConfig {
 		log.Fatal("SECRET_KEY environment variable not set. Please provide it.")
 	}
 
+	runnerEnabled := boolEnv("RUNNER_ENABLED")
+	runnerUID := uint32(positiveIntEnv("RUNNER_UID", 0))
+	runnerGID := uint32(positiveIntEnv("RUNNER_GID", int(runnerUID)))
+	if runnerEnabled && runnerUID == 0 {
+		log.Fatal("RUNNER_UID not set. Submissions only run as an unprivileged user of their own.")
+	}
+	if runnerEnabled && os.Geteuid() != 0 {
+		log.Fatal("RUNNER_ENABLED needs the server to run as root, to isolate submissions.")
+	}
+
 	return &AppConfig{
-		ServerPort: ":" + port,
-		DbURL:      dbURL,
-		SecretKey:  secretKey,
+		ServerPort:           ":" + port,
+		DbURL:                dbURL,
+		SecretKey:            secretKey,
+		GraderWorkers:        positiveIntEnv("GRADER_WORKERS", 4),
+		GraderConcurrency:    positiveIntEnv("GRADER_CONCURRENCY", 4),
+		GraderTestTimeout:    durationEnv("GRADER_TEST_TIMEOUT", 10*time.Second),
+		GraderRunTimeout:     durationEnv("GRADER_RUN_TIMEOUT", 10*time.Minute),
+		GraderAllowedTargets: targetsEnv("GRADER_ALLOWED_TARGETS"),
+		GraderBlockedTargets: targetsEnv("GRADER_BLOCKED_TARGETS"),
+		SubmissionDir:        stringEnv("SUBMISSION_DIR", "data/submissions"),
+		SubmissionMaxSize:    int64(positiveIntEnv("SUBMISSION_MAX_SIZE_MB", 50)) << 20,
+		RunnerEnabled:        runnerEnabled,
+		RunnerCommand:        stringEnv("RUNNER_COMMAND", "./start.sh"),
+		RunnerHealthPath:     stringEnv("RUNNER_HEALTH_PATH", "/"),
+		RunnerStartTimeout:   durationEnv("RUNNER_START_TIMEOUT", 30*time.Second),
+		RunnerWallTime:       durationEnv("RUNNER_WALL_TIME", 15*time.Minute),
+		RunnerCPUTime:        durationEnv("RUNNER_CPU_TIME", time.Minute),
+		RunnerMemory:         int64(positiveIntEnv("RUNNER_MEMORY_MB", 1024)) << 20,
+		RunnerScratchDir:     os.Getenv("RUNNER_SCRATCH_DIR"),
+		RunnerUID:            runnerUID,
+		RunnerGID:            runnerGID,
+		AccessTokenTTL:       durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:      durationEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+	}
+}
+
+// Targets is a list of hosts and networks the grader may or may not send requests to.
+type Targets struct {
+	Hosts []string
+	Nets  []netip.Prefix
+}
+
+// targetsEnv reads a comma separated list of CIDRs, addresses and host names such as
+// "10.1.0.0/16, 192.168.1.5, grader.example.com, *.example.org" from the environment.
+func targetsEnv(key string) Targets {
+	var targets Targets
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				log.Fatalf("%s: invalid network %q.", key, entry)
+			}
+			targets.Nets = append(targets.Nets, prefix.Masked())
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				targets.Nets = append(targets.Nets, netip.PrefixFrom(addr, addr.BitLen()))
+			} else if strings.ContainsAny(entry, " :[]") {
+				log.Fatalf("%s: invalid host %q.", key, entry)
+			} else {
+				targets.Hosts = append(targets.Hosts, entry)
+			}
+		}
+	}
+	return targets
+}
+
+// positiveIntEnv reads a positive integer from the environment, falling back to def when unset.
+func positiveIntEnv(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log.Fatalf("%s must be a positive integer.", key)
+	}
+	return n
+}
+
+// stringEnv reads a string from the environment, falling back to def when unset.
+func stringEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// boolEnv reads a flag such as "true" or "1" from the environment, which is off when unset.
+func boolEnv(key string) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("%s must be true or false.", key)
+	}
+	return b
+}
+
+// durationEnv reads a duration such as "30s" from the environment, falling back to def when unset.
+func durationEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s must be a positive duration, e.g. 30s.", key)
 	}
+	return d
 }
@@ -0,0 +1,342 @@
+package grader
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// An assertion checks one value of a JSON response body. It is written as
+// "path op value", e.g.
+//
+//	$.items.length > 0
+//	$.user.email == {{email}}
+//	$[0].id exists
+//	$.user.password !exists
+//
+// Paths are a subset of JSONPath: $ is the body, .name or ['name'] a key of an object,
+// [i] an element of an array, counting from the end if negative, and a trailing .length
+// the length of an array, object or string. Values are JSON, with {{var_name}}
+// substituted first; anything that isn't JSON is taken as a string.
+type assertion struct {
+	Path  []pathStep
+	Op    AssertionOp
+	Value string
+}
+
+// AssertionOp is how an assertion compares the value at its path.
+type AssertionOp string
+
+const (
+	OpEqual        AssertionOp = "=="
+	OpNotEqual     AssertionOp = "!="
+	OpGreater      AssertionOp = ">"
+	OpGreaterEqual AssertionOp = ">="
+	OpLess         AssertionOp = "<"
+	OpLessEqual    AssertionOp = "<="
+	OpContains     AssertionOp = "contains" // a substring, an array element or an object key
+	OpMatches      AssertionOp = "matches"  // a regular expression, for strings
+	OpExists       AssertionOp = "exists"
+	OpNotExists    AssertionOp = "!exists"
+)
+
+var assertionOps = []AssertionOp{OpEqual, OpNotEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual, OpContains, OpMatches, OpExists, OpNotExists}
+
+// pathStep is a key of an object or, if Key is empty, an element of an array. Any
+// stands for every key or element, written [*] or .*; only comparisons may use it.
+type pathStep struct {
+	Key   string
+	Index int
+	Any   bool
+}
+
+func parseAssertion(expr string) (*assertion, error) {
+	expr = strings.TrimSpace(expr)
+	pathEnd := pathLength(expr)
+	path, err := parsePath(expr[:pathEnd])
+	if err != nil {
+		return nil, fmt.Errorf("%w '%s': %s", ErrInvalidAssertion, expr, err.Error())
+	}
+	for _, step := range path {
+		if step.Any {
+			return nil, fmt.Errorf("%w '%s': wildcards can't be used in assertions", ErrInvalidAssertion, expr)
+		}
+	}
+
+	rest := strings.TrimSpace(expr[pathEnd:])
+	opStr, value, _ := strings.Cut(rest, " ")
+	a := &assertion{Path: path, Op: AssertionOp(opStr), Value: strings.TrimSpace(value)}
+
+	switch {
+	case opStr == "":
+		return nil, fmt.Errorf("%w '%s': expected 'path op value'", ErrInvalidAssertion, expr)
+	case !containsOp(a.Op):
+		return nil, fmt.Errorf("%w '%s': unknown operator '%s'", ErrInvalidAssertion, expr, opStr)
+	case (a.Op == OpExists || a.Op == OpNotExists) && a.Value != "":
+		return nil, fmt.Errorf("%w '%s': %s takes no value", ErrInvalidAssertion, expr, a.Op)
+	case a.Op != OpExists && a.Op != OpNotExists && a.Value == "":
+		return nil, fmt.Errorf("%w '%s': %s needs a value", ErrInvalidAssertion, expr, a.Op)
+	}
+	if a.Op == OpMatches && !substitutionRegex.MatchString(a.Value) {
+		if _, err := regexp.Compile(stringValue(a.Value)); err != nil {
+			return nil, fmt.Errorf("%w '%s': %s", ErrInvalidAssertion, expr, err.Error())
+		}
+	}
+	return a, nil
+}
+
+func containsOp(op AssertionOp) bool {
+	for _, known := range assertionOps {
+		if op == known {
+			return true
+		}
+	}
+	return false
+}
+
+// pathLength returns where the path at the start of expr ends: at the first space
+// outside of brackets.
+func pathLength(expr string) int {
+	depth, quote := 0, byte(0)
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == ' ' && depth == 0:
+			return i
+		}
+	}
+	return len(expr)
+}
+
+func parsePath(path string) ([]pathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path '%s' doesn't start with $", path)
+	}
+
+	var steps []pathStep
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := 1
+			for end < len(rest) && rest[end] != '.' && rest[end] != '[' {
+				end++
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("path '%s' has an empty key", path)
+			}
+			if rest[1:end] == "*" {
+				steps = append(steps, pathStep{Any: true})
+			} else {
+				steps = append(steps, pathStep{Key: rest[1:end]})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("path '%s' has an unclosed [", path)
+			}
+			inner := rest[1:end]
+			if inner == "*" {
+				steps = append(steps, pathStep{Any: true})
+			} else if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				steps = append(steps, pathStep{Key: inner[1 : len(inner)-1]})
+			} else if index, err := strconv.Atoi(inner); err == nil {
+				steps = append(steps, pathStep{Index: index})
+			} else {
+				return nil, fmt.Errorf("path '%s' has '[%s]', expected an index, a quoted key or *", path, inner)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("path '%s' has '%c' where . or [ was expected", path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// lookup returns the value at path in body, and whether there is one.
+func lookup(body interface{}, path []pathStep) (interface{}, bool) {
+	value := body
+	for i, step := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if step.Key == "" {
+				return nil, false
+			}
+			next, ok := v[step.Key]
+			if !ok {
+				if step.Key == "length" && i == len(path)-1 {
+					return float64(len(v)), true
+				}
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			if step.Key == "length" && i == len(path)-1 {
+				return float64(len(v)), true
+			}
+			index := step.Index
+			if index < 0 {
+				index += len(v)
+			}
+			if step.Key != "" || index < 0 || index >= len(v) {
+				return nil, false
+			}
+			value = v[index]
+		case string:
+			if step.Key == "length" && i == len(path)-1 {
+				return float64(len([]rune(v))), true
+			}
+			return nil, false
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// jsonValue parses an assertion's value as JSON, falling back to a string.
+func jsonValue(value string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return value
+	}
+	return v
+}
+
+// stringValue is value as a string, without its quotes if it's a JSON string.
+func stringValue(value string) string {
+	if s, ok := jsonValue(value).(string); ok {
+		return s
+	}
+	return value
+}
+
+// checkAssertion evaluates an assertion against a response body, which is nil with
+// bodyErr set if the body isn't JSON.
+func (gd *Grader) checkAssertion(expr string, body interface{}, bodyErr error) AssertionResult {
+	result := AssertionResult{Expr: expr}
+	a, err := parseAssertion(expr)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if bodyErr != nil {
+		result.Message = fmt.Sprintf("response body is not JSON: %v", bodyErr)
+		return result
+	}
+
+	actual, found := lookup(body, a.Path)
+	if found {
+		raw, _ := json.Marshal(actual)
+		result.Actual = string(raw)
+	}
+	if a.Op == OpExists || a.Op == OpNotExists {
+		result.Passed = found == (a.Op == OpExists)
+		if !result.Passed && found {
+			result.Message = "expected nothing at the path"
+		} else if !result.Passed {
+			result.Message = "nothing at the path"
+		}
+		return result
+	}
+	if !found {
+		result.Message = "nothing at the path"
+		return result
+	}
+
+	value := gd.substituteVariables(a.Value)
+	result.Passed, err = compareAssertion(actual, a.Op, value)
+	if err != nil {
+		result.Message = err.Error()
+	} else if !result.Passed {
+		result.Message = fmt.Sprintf("expected %s %s %s", result.Actual, a.Op, value)
+	}
+	return result
+}
+
+func compareAssertion(actual interface{}, op AssertionOp, value string) (bool, error) {
+	expected := jsonValue(value)
+	switch op {
+	case OpEqual:
+		return sameJSON(actual, expected), nil
+	case OpNotEqual:
+		return !sameJSON(actual, expected), nil
+	case OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+		a, ok := actual.(float64)
+		if !ok {
+			return false, fmt.Errorf("%s compares numbers, got %T", op, actual)
+		}
+		e, ok := expected.(float64)
+		if !ok {
+			return false, fmt.Errorf("%s compares numbers, got '%s'", op, value)
+		}
+		switch op {
+		case OpGreater:
+			return a > e, nil
+		case OpGreaterEqual:
+			return a >= e, nil
+		case OpLess:
+			return a < e, nil
+		default:
+			return a <= e, nil
+		}
+	case OpContains:
+		switch a := actual.(type) {
+		case string:
+			return strings.Contains(a, stringValue(value)), nil
+		case []interface{}:
+			for _, item := range a {
+				if sameJSON(item, expected) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			_, ok := a[stringValue(value)]
+			return ok, nil
+		}
+		return false, fmt.Errorf("contains looks into strings, arrays and objects, got %T", actual)
+	case OpMatches:
+		a, ok := actual.(string)
+		if !ok {
+			return false, fmt.Errorf("matches compares strings, got %T", actual)
+		}
+		re, err := regexp.Compile(stringValue(value))
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(a), nil
+	}
+	return false, fmt.Errorf("unknown operator '%s'", op)
+}
+
+// sameJSON tells if two decoded JSON values are equal. Like jsonValueEquals, scalars
+// are compared by how they print, so that 7 and "7" are the same.
+func sameJSON(a, b interface{}) bool {
+	switch a.(type) {
+	case map[string]interface{}, []interface{}:
+		return reflect.DeepEqual(a, b)
+	}
+	switch b.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
+}
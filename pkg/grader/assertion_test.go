@@ -0,0 +1,236 @@
+package grader
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAssertion(t *testing.T) {
+	tests := []struct {
+		expr string
+		want *assertion
+		err  string // part of the error, if parsing should fail
+	}{
+		{expr: "$.items.length > 0", want: &assertion{Path: []pathStep{{Key: "items"}, {Key: "length"}}, Op: OpGreater, Value: "0"}},
+		{expr: "$.user.email == {{email}}", want: &assertion{Path: []pathStep{{Key: "user"}, {Key: "email"}}, Op: OpEqual, Value: "{{email}}"}},
+		{expr: "$[0].id exists", want: &assertion{Path: []pathStep{{Index: 0}, {Key: "id"}}, Op: OpExists}},
+		{expr: "$.user.password !exists", want: &assertion{Path: []pathStep{{Key: "user"}, {Key: "password"}}, Op: OpNotExists}},
+		{expr: "$.items[-1].name != \"bob\"", want: &assertion{Path: []pathStep{{Key: "items"}, {Index: -1}, {Key: "name"}}, Op: OpNotEqual, Value: `"bob"`}},
+		{expr: "$['a b'] contains x y", want: &assertion{Path: []pathStep{{Key: "a b"}}, Op: OpContains, Value: "x y"}},
+		{expr: `$["a.b"][1] <= 2`, want: &assertion{Path: []pathStep{{Key: "a.b"}, {Index: 1}}, Op: OpLessEqual, Value: "2"}},
+		{expr: "  $ == {}  ", want: &assertion{Op: OpEqual, Value: "{}"}},
+		{expr: "$.name matches ^b.+$", want: &assertion{Path: []pathStep{{Key: "name"}}, Op: OpMatches, Value: "^b.+$"}},
+
+		{expr: "$.a.length>0", err: "expected 'path op value'"},
+		{expr: "$[x] == 1", err: "expected an index, a quoted key or *"},
+		{expr: "$[0 == 1", err: "unclosed ["},
+		{expr: "$..a exists", err: "empty key"},
+		{expr: "items == 1", err: "doesn't start with $"},
+		{expr: "$a == 1", err: "where . or [ was expected"},
+		{expr: "$.items[*].id exists", err: "wildcards can't be used"},
+		{expr: "$.a ~ 1", err: "unknown operator '~'"},
+		{expr: "$.a exists 1", err: "exists takes no value"},
+		{expr: "$.a ==", err: "== needs a value"},
+		{expr: "$.a matches [", err: "missing closing ]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := parseAssertion(tt.expr)
+			if tt.err != "" {
+				if !errors.Is(err, ErrInvalidAssertion) || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one about %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []pathStep
+		err  bool
+	}{
+		{path: "$"},
+		{path: "$.a", want: []pathStep{{Key: "a"}}},
+		{path: "$.a[2].b", want: []pathStep{{Key: "a"}, {Index: 2}, {Key: "b"}}},
+		{path: "$[-2]", want: []pathStep{{Index: -2}}},
+		{path: "$['a b']", want: []pathStep{{Key: "a b"}}},
+		{path: `$["length"]`, want: []pathStep{{Key: "length"}}},
+		{path: "$.users[*].name", want: []pathStep{{Key: "users"}, {Any: true}, {Key: "name"}}},
+		{path: "$.users.*", want: []pathStep{{Key: "users"}, {Any: true}}},
+		{path: "$[x]", err: true},
+		{path: "$['a]", err: true},
+		{path: "$[]", err: true},
+		{path: "$.", err: true},
+		{path: ".a", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parsePath(tt.path)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var body interface{}
+	err := json.Unmarshal([]byte(`{
+		"items": [{"id": 1}, {"id": 2}, {"id": 3}],
+		"user": {"name": "bób", "email": "bob@example.com"},
+		"page": {"length": 20, "offset": 0},
+		"a b": true,
+		"empty": null
+	}`), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		want  interface{}
+		found bool
+	}{
+		{path: "$.items.length", want: 3.0, found: true},
+		{path: "$.items[0].id", want: 1.0, found: true},
+		{path: "$.items[-1].id", want: 3.0, found: true},
+		{path: "$.items[-3].id", want: 1.0, found: true},
+		{path: "$.items[-4]"},
+		{path: "$.items[3]"},
+		{path: "$.items.first"},
+		{path: "$.user.length", want: 2.0, found: true},
+		{path: "$.user.name.length", want: 3.0, found: true},
+		{path: "$.page.length", want: 20.0, found: true},
+		{path: `$.page["length"]`, want: 20.0, found: true},
+		{path: "$.items.length.x"},
+		{path: "$.user.length.x"},
+		{path: "$['a b']", want: true, found: true},
+		{path: "$.empty", want: nil, found: true},
+		{path: "$.empty.x"},
+		{path: "$[0]"},
+		{path: "$.missing"},
+		{path: "$.user.email.x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := parsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, found := lookup(body, path)
+			if found != tt.found || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, %v; want %v, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestCompareAssertion(t *testing.T) {
+	tests := []struct {
+		actual string // JSON
+		op     AssertionOp
+		value  string
+		want   bool
+		err    bool
+	}{
+		{actual: `"bob@example.com"`, op: OpEqual, value: "bob@example.com", want: true},
+		{actual: `"bob@example.com"`, op: OpEqual, value: `"bob@example.com"`, want: true},
+		{actual: `7`, op: OpEqual, value: `"7"`, want: true},
+		{actual: `7`, op: OpNotEqual, value: "8", want: true},
+		{actual: `null`, op: OpEqual, value: "null", want: true},
+		{actual: `null`, op: OpEqual, value: `""`},
+		{actual: `{"a": [1, 2]}`, op: OpEqual, value: `{"a":[1,2]}`, want: true},
+		{actual: `[1, 2]`, op: OpEqual, value: `[2, 1]`},
+		{actual: `[1]`, op: OpEqual, value: "1"},
+		{actual: `3`, op: OpGreater, value: "0", want: true},
+		{actual: `0`, op: OpGreater, value: "0"},
+		{actual: `0`, op: OpGreaterEqual, value: "0", want: true},
+		{actual: `-1.5`, op: OpLess, value: "-1", want: true},
+		{actual: `2`, op: OpLessEqual, value: "1"},
+		{actual: `"3"`, op: OpGreater, value: "0", err: true},
+		{actual: `3`, op: OpGreater, value: "three", err: true},
+		{actual: `"hello world"`, op: OpContains, value: "lo wo", want: true},
+		{actual: `"hello"`, op: OpContains, value: `"ell"`, want: true},
+		{actual: `[1, "a", {"b": 2}]`, op: OpContains, value: `{"b": 2}`, want: true},
+		{actual: `[1, 2]`, op: OpContains, value: "3"},
+		{actual: `{"id": 1}`, op: OpContains, value: "id", want: true},
+		{actual: `{"id": 1}`, op: OpContains, value: "name"},
+		{actual: `5`, op: OpContains, value: "5", err: true},
+		{actual: `"bob"`, op: OpMatches, value: "^b.b$", want: true},
+		{actual: `"bob"`, op: OpMatches, value: `"^a"`},
+		{actual: `5`, op: OpMatches, value: "5", err: true},
+		{actual: `"bob"`, op: OpMatches, value: "[", err: true},
+		{actual: `"bob"`, op: "~", value: "bob", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.actual+" "+string(tt.op)+" "+tt.value, func(t *testing.T) {
+			var actual interface{}
+			if err := json.Unmarshal([]byte(tt.actual), &actual); err != nil {
+				t.Fatal(err)
+			}
+			got, err := compareAssertion(actual, tt.op, tt.value)
+			if (err != nil) != tt.err {
+				t.Fatalf("error %v, want one: %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAssertion(t *testing.T) {
+	gd := NewGrader("", 0)
+	gd.variables["email"] = "bob@example.com"
+	var body interface{}
+	json.Unmarshal([]byte(`{"user": {"email": "bob@example.com", "password": null}, "items": []}`), &body)
+
+	tests := []struct {
+		expr    string
+		passed  bool
+		actual  string
+		message string
+	}{
+		{expr: "$.user.email == {{email}}", passed: true, actual: `"bob@example.com"`},
+		{expr: "$.items.length > 0", actual: "0", message: "expected 0 > 0"},
+		{expr: "$.user.password !exists", actual: "null", message: "expected nothing at the path"},
+		{expr: "$.user.name exists", message: "nothing at the path"},
+		{expr: "$.user.name == bob", message: "nothing at the path"},
+		{expr: "$.user.email > 1", actual: `"bob@example.com"`, message: "> compares numbers"},
+		{expr: "$[x] exists", message: "invalid assertion"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got := gd.checkAssertion(tt.expr, body, nil)
+			if got.Expr != tt.expr || got.Passed != tt.passed || got.Actual != tt.actual || !strings.Contains(got.Message, tt.message) {
+				t.Errorf("got %+v, want passed %v, actual %q and a message with %q", got, tt.passed, tt.actual, tt.message)
+			}
+		})
+	}
+
+	got := gd.checkAssertion("$.a exists", nil, errors.New("bad body"))
+	if got.Passed || !strings.Contains(got.Message, "not JSON") {
+		t.Errorf("got %+v for a body that isn't JSON", got)
+	}
+}
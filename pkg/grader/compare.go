@@ -0,0 +1,123 @@
+package grader
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ObjectMode is how an expected JSON object is compared with the actual one.
+type ObjectMode string
+
+const (
+	// ObjectsSubset lets the actual object have keys the expected one doesn't. It's the default.
+	ObjectsSubset ObjectMode = "subset"
+	// ObjectsStrict refuses extra keys, e.g. to catch a password leaking into a response.
+	ObjectsStrict ObjectMode = "strict"
+)
+
+// ArrayMode is how an expected JSON array is compared with the actual one.
+type ArrayMode string
+
+const (
+	// ArraysUnordered wants the same elements in any order. It's the default.
+	ArraysUnordered ArrayMode = "unordered"
+	// ArraysOrdered wants the same elements in the same order, e.g. to grade sorting.
+	ArraysOrdered ArrayMode = "ordered"
+	// ArraysContains wants the expected elements among the actual ones, which may have more.
+	ArraysContains ArrayMode = "contains"
+)
+
+var (
+	objectModes = []ObjectMode{ObjectsSubset, ObjectsStrict}
+	arrayModes  = []ArrayMode{ArraysUnordered, ArraysOrdered, ArraysContains}
+)
+
+// comparison is how the expected body is compared with the actual one at some point
+// of it. Modes apply to everything below the point until a rule names a deeper one.
+type comparison struct {
+	path    []pathStep
+	objects ObjectMode
+	arrays  ArrayMode
+	rules   []comparisonRule
+}
+
+type comparisonRule struct {
+	path    []pathStep
+	objects ObjectMode
+	arrays  ArrayMode
+}
+
+// newComparison returns the comparison at the root of a test's response body. Rule paths
+// name array elements with [*] only: in unordered and contains modes an expected element
+// may be compared with any actual one, so an index would name neither for sure.
+func newComparison(resp TResponse) (comparison, error) {
+	c := comparison{objects: ObjectsSubset, arrays: ArraysUnordered}
+	if err := checkModes(resp.ObjectMode, resp.ArrayMode); err != nil {
+		return c, fmt.Errorf("%w: %s", ErrInvalidComparison, err.Error())
+	}
+	c.apply(resp.ObjectMode, resp.ArrayMode)
+
+	for _, tc := range resp.Comparisons {
+		if err := checkModes(tc.ObjectMode, tc.ArrayMode); err != nil {
+			return c, fmt.Errorf("%w at '%s': %s", ErrInvalidComparison, tc.Path, err.Error())
+		}
+		path, err := parsePath(tc.Path)
+		if err != nil {
+			return c, fmt.Errorf("%w: %s", ErrInvalidComparison, err.Error())
+		}
+		for _, step := range path {
+			if step.Key == "" && !step.Any {
+				return c, fmt.Errorf("%w at '%s': array elements can only be named with [*]", ErrInvalidComparison, tc.Path)
+			}
+		}
+		c.rules = append(c.rules, comparisonRule{path: path, objects: tc.ObjectMode, arrays: tc.ArrayMode})
+	}
+	return c.at(nil), nil
+}
+
+func checkModes(objects ObjectMode, arrays ArrayMode) error {
+	if objects != "" && !slices.Contains(objectModes, objects) {
+		return fmt.Errorf("object mode '%s', expected one of %v", objects, objectModes)
+	}
+	if arrays != "" && !slices.Contains(arrayModes, arrays) {
+		return fmt.Errorf("array mode '%s', expected one of %v", arrays, arrayModes)
+	}
+	return nil
+}
+
+func (c *comparison) apply(objects ObjectMode, arrays ArrayMode) {
+	if objects != "" {
+		c.objects = objects
+	}
+	if arrays != "" {
+		c.arrays = arrays
+	}
+}
+
+// at returns the comparison one step below c, or at c itself if step is nil, with the
+// rules naming that point applied in order.
+func (c comparison) at(step *pathStep) comparison {
+	next := c
+	if step != nil {
+		next.path = append(slices.Clip(c.path), *step)
+	}
+	for _, rule := range c.rules {
+		if pathMatches(rule.path, next.path) {
+			next.apply(rule.objects, rule.arrays)
+		}
+	}
+	return next
+}
+
+// pathMatches tells if path, which has no wildcards, is the one pattern names.
+func pathMatches(pattern, path []pathStep) bool {
+	if len(pattern) != len(path) {
+		return false
+	}
+	for i, p := range pattern {
+		if !p.Any && p != path[i] {
+			return false
+		}
+	}
+	return true
+}
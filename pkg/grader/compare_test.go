@@ -0,0 +1,229 @@
+package grader
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewComparison(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    TResponse
+		objects ObjectMode
+		arrays  ArrayMode
+		err     string // part of the error, if the comparison should be refused
+	}{
+		{name: "defaults", objects: ObjectsSubset, arrays: ArraysUnordered},
+		{name: "root modes", resp: TResponse{ObjectMode: ObjectsStrict, ArrayMode: ArraysOrdered}, objects: ObjectsStrict, arrays: ArraysOrdered},
+		{
+			name:    "root rule",
+			resp:    TResponse{ArrayMode: ArraysOrdered, Comparisons: []TComparison{{Path: "$", ArrayMode: ArraysContains}}},
+			objects: ObjectsSubset, arrays: ArraysContains,
+		},
+		{
+			name:    "wildcards",
+			resp:    TResponse{Comparisons: []TComparison{{Path: "$.users[*].tags", ArrayMode: ArraysOrdered}, {Path: "$.*", ObjectMode: ObjectsStrict}}},
+			objects: ObjectsSubset, arrays: ArraysUnordered,
+		},
+		{name: "unknown object mode", resp: TResponse{ObjectMode: "exact"}, err: "object mode 'exact'"},
+		{name: "unknown array mode", resp: TResponse{Comparisons: []TComparison{{Path: "$.items", ArrayMode: "sorted"}}}, err: "at '$.items': array mode 'sorted'"},
+		{name: "bad path", resp: TResponse{Comparisons: []TComparison{{Path: "items", ArrayMode: ArraysOrdered}}}, err: "doesn't start with $"},
+		{name: "index", resp: TResponse{Comparisons: []TComparison{{Path: "$.items[0]", ObjectMode: ObjectsStrict}}}, err: "only be named with [*]"},
+		{name: "negative index", resp: TResponse{Comparisons: []TComparison{{Path: "$.items[-1].tags", ArrayMode: ArraysOrdered}}}, err: "only be named with [*]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmp, err := newComparison(tt.resp)
+			if tt.err != "" {
+				if !errors.Is(err, ErrInvalidComparison) || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one about %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cmp.objects != tt.objects || cmp.arrays != tt.arrays {
+				t.Errorf("got %s/%s, want %s/%s", cmp.objects, cmp.arrays, tt.objects, tt.arrays)
+			}
+			if len(cmp.rules) != len(tt.resp.Comparisons) {
+				t.Errorf("%d rules, want %d", len(cmp.rules), len(tt.resp.Comparisons))
+			}
+		})
+	}
+}
+
+func TestComparisonAt(t *testing.T) {
+	root, err := newComparison(TResponse{
+		ObjectMode: ObjectsStrict,
+		Comparisons: []TComparison{
+			{Path: "$.users", ArrayMode: ArraysOrdered},
+			{Path: "$.users[*]", ObjectMode: ObjectsSubset},
+			{Path: "$.users[*].tags", ArrayMode: ArraysUnordered},
+			{Path: "$.*.tags", ArrayMode: ArraysContains},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		steps   []pathStep
+		objects ObjectMode
+		arrays  ArrayMode
+	}{
+		{name: "$", objects: ObjectsStrict, arrays: ArraysUnordered},
+		{name: "$.users", steps: []pathStep{{Key: "users"}}, objects: ObjectsStrict, arrays: ArraysOrdered},
+		{name: "$.users[3]", steps: []pathStep{{Key: "users"}, {Index: 3}}, objects: ObjectsSubset, arrays: ArraysOrdered},
+		// The later rule for $.*.tags doesn't reach this deep.
+		{name: "$.users[0].tags", steps: []pathStep{{Key: "users"}, {Index: 0}, {Key: "tags"}}, objects: ObjectsSubset, arrays: ArraysUnordered},
+		{name: "$.users[0].name", steps: []pathStep{{Key: "users"}, {Index: 0}, {Key: "name"}}, objects: ObjectsSubset, arrays: ArraysOrdered},
+		{name: "$.team.tags", steps: []pathStep{{Key: "team"}, {Key: "tags"}}, objects: ObjectsStrict, arrays: ArraysContains},
+		{name: "$.team.name", steps: []pathStep{{Key: "team"}, {Key: "name"}}, objects: ObjectsStrict, arrays: ArraysUnordered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmp := root
+			for _, step := range tt.steps {
+				cmp = cmp.at(&step)
+			}
+			if cmp.objects != tt.objects || cmp.arrays != tt.arrays {
+				t.Errorf("got %s/%s, want %s/%s", cmp.objects, cmp.arrays, tt.objects, tt.arrays)
+			}
+		})
+	}
+
+	// Siblings don't share their paths' backing array.
+	users := root.at(&pathStep{Key: "users"})
+	first, second := users.at(&pathStep{Index: 0}), users.at(&pathStep{Index: 1})
+	if first.path[1].Index != 0 || second.path[1].Index != 1 {
+		t.Errorf("sibling paths %+v and %+v", first.path, second.path)
+	}
+}
+
+func TestPathMatches(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{pattern: "$", path: "$", want: true},
+		{pattern: "$.a", path: "$.a", want: true},
+		{pattern: "$.a", path: "$['a']", want: true},
+		{pattern: "$.a", path: "$.b"},
+		{pattern: "$.a", path: "$.a.b"},
+		{pattern: "$.a.b", path: "$.a"},
+		{pattern: "$.a[*]", path: "$.a[0]", want: true},
+		{pattern: "$.a[*]", path: "$.a[12]", want: true},
+		{pattern: "$.a.*", path: "$.a.b", want: true},
+		{pattern: "$[*].b", path: "$[1].b", want: true},
+		{pattern: "$[*].b", path: "$[1].c"},
+		{pattern: "$.length", path: "$[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			pattern, err := parsePath(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path, err := parsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pathMatches(pattern, path); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareModes(t *testing.T) {
+	tests := []struct {
+		name             string
+		actual, expected string
+		resp             TResponse
+		want             bool
+	}{
+		{name: "unordered", actual: `[1, 2, 3]`, expected: `[3, 1, 2]`, want: true},
+		{name: "unordered length", actual: `[1, 2, 3]`, expected: `[1, 2]`},
+		{name: "unordered duplicates", actual: `[1, 1, 2]`, expected: `[1, 2, 2]`},
+		{name: "ordered", actual: `[1, 2, 3]`, expected: `[1, 2, 3]`, resp: TResponse{ArrayMode: ArraysOrdered}, want: true},
+		{name: "ordered swapped", actual: `[1, 2, 3]`, expected: `[1, 3, 2]`, resp: TResponse{ArrayMode: ArraysOrdered}},
+		{name: "contains", actual: `[1, 2, 3]`, expected: `[3, 1]`, resp: TResponse{ArrayMode: ArraysContains}, want: true},
+		{name: "contains missing", actual: `[1, 2, 3]`, expected: `[4]`, resp: TResponse{ArrayMode: ArraysContains}},
+		{name: "contains empty", actual: `[]`, expected: `[]`, resp: TResponse{ArrayMode: ArraysContains}, want: true},
+		{name: "subset", actual: `{"id": 1, "name": "bob"}`, expected: `{"id": 1}`, want: true},
+		{name: "strict", actual: `{"id": 1, "name": "bob"}`, expected: `{"id": 1}`, resp: TResponse{ObjectMode: ObjectsStrict}},
+		{name: "strict exact", actual: `{"id": 1}`, expected: `{"id": 1}`, resp: TResponse{ObjectMode: ObjectsStrict}, want: true},
+		{
+			name:   "ordered below unordered",
+			actual: `{"sorted": [1, 2, 3], "tags": ["b", "a"]}`, expected: `{"sorted": [1, 2, 3], "tags": ["a", "b"]}`,
+			resp: TResponse{Comparisons: []TComparison{{Path: "$.sorted", ArrayMode: ArraysOrdered}}},
+			want: true,
+		},
+		{
+			name:   "ordered below unordered swapped",
+			actual: `{"sorted": [1, 3, 2], "tags": ["b", "a"]}`, expected: `{"sorted": [1, 2, 3], "tags": ["a", "b"]}`,
+			resp: TResponse{Comparisons: []TComparison{{Path: "$.sorted", ArrayMode: ArraysOrdered}}},
+		},
+		{
+			name:   "strict elements only",
+			actual: `{"total": 2, "users": [{"id": 1}, {"id": 2}]}`, expected: `{"users": [{"id": 2}, {"id": 1}]}`,
+			resp: TResponse{Comparisons: []TComparison{{Path: "$.users[*]", ObjectMode: ObjectsStrict}}},
+			want: true,
+		},
+		{
+			name:   "strict elements leaking",
+			actual: `{"users": [{"id": 1}, {"id": 2, "password": "x"}]}`, expected: `{"users": [{"id": 2}, {"id": 1}]}`,
+			resp: TResponse{Comparisons: []TComparison{{Path: "$.users[*]", ObjectMode: ObjectsStrict}}},
+		},
+		{
+			// Whichever actual user an expected one is tried against, the rule applies.
+			name:   "contains in unordered elements",
+			actual: `[{"id": 1, "tags": ["x", "y"]}, {"id": 2, "tags": ["z"]}]`, expected: `[{"id": 2, "tags": ["z"]}, {"id": 1, "tags": ["y"]}]`,
+			resp: TResponse{Comparisons: []TComparison{{Path: "$[*].tags", ArrayMode: ArraysContains}}},
+			want: true,
+		},
+		{
+			name:   "contains in contains elements",
+			actual: `[{"id": 1, "tags": ["x", "y"]}, {"id": 2, "tags": ["z"]}, {"id": 3}]`, expected: `[{"id": 1, "tags": ["y"]}]`,
+			resp: TResponse{ArrayMode: ArraysContains},
+			want: true,
+		},
+		{
+			name:   "ordered rows in unordered matrix",
+			actual: `[[1, 2], [3, 4]]`, expected: `[[3, 4], [1, 2]]`,
+			resp: TResponse{Comparisons: []TComparison{{Path: "$[*]", ArrayMode: ArraysOrdered}}},
+			want: true,
+		},
+		{
+			name:   "ordered rows swapped",
+			actual: `[[1, 2], [3, 4]]`, expected: `[[4, 3], [1, 2]]`,
+			resp: TResponse{Comparisons: []TComparison{{Path: "$[*]", ArrayMode: ArraysOrdered}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actual, expected interface{}
+			if err := json.Unmarshal([]byte(tt.actual), &actual); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.expected), &expected); err != nil {
+				t.Fatal(err)
+			}
+			cmp, err := newComparison(tt.resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewGrader("", 0).jsonValueEquals(actual, expected, cmp)
+			if got != tt.want {
+				t.Errorf("got %v (%v), want %v", got, err, tt.want)
+			}
+			if !got && err == nil {
+				t.Error("a mismatch without an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package grader
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// dependencyNode is a section, scenario or test as seen by the scheduler.
+type dependencyNode interface {
+	nodeID() uint
+	nodeName() string
+	prerequisites() []uint
+}
+
+func (sp *sectionPlan) nodeID() uint          { return sp.Section.ID }
+func (sp *sectionPlan) nodeName() string      { return sp.Section.Name }
+func (sp *sectionPlan) prerequisites() []uint { return sp.Section.DependsOnIDs }
+
+func (sp *scenarioPlan) nodeID() uint          { return sp.Scenario.ID }
+func (sp *scenarioPlan) nodeName() string      { return sp.Scenario.Name }
+func (sp *scenarioPlan) prerequisites() []uint { return sp.Scenario.DependsOnIDs }
+
+func (t Test) nodeID() uint          { return t.ID }
+func (t Test) nodeName() string      { return t.Name }
+func (t Test) prerequisites() []uint { return t.DependsOnIDs }
+
+// validateDependencies checks that nodes only depend on each other and that their
+// dependencies contain no cycle. kind names the nodes in errors, e.g. "test".
+func validateDependencies[T dependencyNode](kind string, nodes []T) error {
+	byID := make(map[uint]T, len(nodes))
+	for _, node := range nodes {
+		byID[node.nodeID()] = node
+	}
+	for _, node := range nodes {
+		for _, prereqID := range node.prerequisites() {
+			if _, ok := byID[prereqID]; !ok {
+				return fmt.Errorf("%w: %s '%s' depends on %s #%d, which is not its sibling", ErrUnknownDependency, kind, node.nodeName(), kind, prereqID)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[uint]int, len(nodes))
+	var path []T
+	var visit func(node T) error
+	visit = func(node T) error {
+		switch state[node.nodeID()] {
+		case visited:
+			return nil
+		case visiting:
+			// The cycle is the part of the path starting at the node we came back to.
+			start := len(path) - 1
+			for path[start].nodeID() != node.nodeID() {
+				start--
+			}
+			var names []string
+			for _, n := range path[start:] {
+				names = append(names, fmt.Sprintf("'%s'", n.nodeName()))
+			}
+			names = append(names, fmt.Sprintf("'%s'", node.nodeName()))
+			return fmt.Errorf("%w between %ss: %s", ErrDependencyCycle, kind, strings.Join(names, " needs "))
+		}
+
+		state[node.nodeID()] = visiting
+		path = append(path, node)
+		for _, prereqID := range node.prerequisites() {
+			if err := visit(byID[prereqID]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[node.nodeID()] = visited
+		return nil
+	}
+
+	for _, node := range nodes {
+		if err := visit(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schedule runs nodes in dependency order, each one only after all of its prerequisites passed.
+// A node with a prerequisite that didn't pass goes to skip instead, along with the failed node
+// that blocked it. Nodes must have passed validateDependencies.
+func schedule[T dependencyNode](nodes []T, run func(node T) bool, skip func(node, blocker T)) {
+	byID := make(map[uint]T, len(nodes))
+	pending := make(map[uint]int, len(nodes))
+	dependents := make(map[uint][]T)
+	var queue []T
+	for _, node := range nodes {
+		byID[node.nodeID()] = node
+		pending[node.nodeID()] = len(node.prerequisites())
+		for _, prereqID := range node.prerequisites() {
+			dependents[prereqID] = append(dependents[prereqID], node)
+		}
+		if len(node.prerequisites()) == 0 {
+			queue = append(queue, node)
+		}
+	}
+
+	passed := make(map[uint]bool, len(nodes))
+	blockers := make(map[uint]T)
+	for i := 0; i < len(queue); i++ {
+		node := queue[i]
+		if blocker, ok := firstBlocker(node, byID, passed, blockers); ok {
+			blockers[node.nodeID()] = blocker
+			skip(node, blocker)
+		} else {
+			passed[node.nodeID()] = run(node)
+		}
+
+		for _, dependent := range dependents[node.nodeID()] {
+			pending[dependent.nodeID()]--
+			if pending[dependent.nodeID()] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+}
+
+// firstBlocker finds the failed node that keeps node from running, if any. A skipped
+// prerequisite passes on the node that blocked it.
+func firstBlocker[T dependencyNode](node T, byID map[uint]T, passed map[uint]bool, blockers map[uint]T) (T, bool) {
+	for _, prereqID := range node.prerequisites() {
+		if passed[prereqID] {
+			continue
+		}
+		if blocker, ok := blockers[prereqID]; ok {
+			return blocker, true
+		}
+		return byID[prereqID], true
+	}
+	var none T
+	return none, false
+}
+
+// scheduleParallel is schedule for independent branches: every node whose prerequisites are done
+// runs right away on its own goroutine. run gets the outputs of the node's prerequisites, in the
+// order of prerequisites(), and scheduleParallel returns the output of every node that ran.
+// Limiting how much work runs at once is up to run.
+func scheduleParallel[T dependencyNode, S any](nodes []T, run func(node T, inputs []S) (S, bool), skip func(node, blocker T)) map[uint]S {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		byID     = make(map[uint]T, len(nodes))
+		pending  = make(map[uint]int, len(nodes))
+		passed   = make(map[uint]bool, len(nodes))
+		blockers = make(map[uint]T)
+		outputs  = make(map[uint]S, len(nodes))
+	)
+	dependents := make(map[uint][]T)
+	for _, node := range nodes {
+		byID[node.nodeID()] = node
+		pending[node.nodeID()] = len(node.prerequisites())
+		for _, prereqID := range node.prerequisites() {
+			dependents[prereqID] = append(dependents[prereqID], node)
+		}
+	}
+
+	var start func(node T)
+	start = func(node T) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			mu.Lock()
+			blocker, blocked := firstBlocker(node, byID, passed, blockers)
+			inputs := make([]S, 0, len(node.prerequisites()))
+			for _, prereqID := range node.prerequisites() {
+				inputs = append(inputs, outputs[prereqID])
+			}
+			if blocked {
+				blockers[node.nodeID()] = blocker
+			}
+			mu.Unlock()
+
+			var output S
+			isPass := false
+			if blocked {
+				skip(node, blocker)
+			} else {
+				output, isPass = run(node, inputs)
+			}
+
+			mu.Lock()
+			passed[node.nodeID()] = isPass
+			if !blocked {
+				outputs[node.nodeID()] = output
+			}
+			var ready []T
+			for _, dependent := range dependents[node.nodeID()] {
+				pending[dependent.nodeID()]--
+				if pending[dependent.nodeID()] == 0 {
+					ready = append(ready, dependent)
+				}
+			}
+			mu.Unlock()
+
+			for _, dependent := range ready {
+				start(dependent)
+			}
+		}()
+	}
+
+	for _, node := range nodes {
+		if len(node.prerequisites()) == 0 {
+			start(node)
+		}
+	}
+	wg.Wait()
+	return outputs
+}
@@ -0,0 +1,19 @@
+package grader
+
+import "errors"
+
+var ErrDependencyCycle = errors.New("dependency cycle")
+var ErrUnknownDependency = errors.New("unknown dependency")
+
+// ErrInvalidSuite is returned for suite files that can't be read or don't describe a project.
+var ErrInvalidSuite = errors.New("invalid suite")
+
+// ErrTargetBlocked is returned for requests the TargetPolicy doesn't allow.
+var ErrTargetBlocked = errors.New("target blocked")
+
+// ErrInvalidAssertion is returned for assertions that can't be parsed.
+var ErrInvalidAssertion = errors.New("invalid assertion")
+
+// ErrInvalidComparison is returned for unknown comparison modes and for paths that can't be
+// parsed or that name array elements by index.
+var ErrInvalidComparison = errors.New("invalid comparison")
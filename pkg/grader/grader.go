@@ -1,9 +1,16 @@
 package grader
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
+	"net/http"
 	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"gorm.io/gorm"
@@ -15,221 +22,489 @@ var (
 )
 
 type Grader struct {
-	BaseUrl   string
-	UserID    uint
+	BaseUrl string
+	UserID  uint
+	// Concurrency is how many scenarios may run against BaseUrl at the same time.
+	Concurrency int
+	// TestTimeout bounds each test's request and RunTimeout the whole run. Zero means no limit.
+	TestTimeout time.Duration
+	RunTimeout  time.Duration
+	// TargetPolicy limits where requests may go. Nil means anywhere.
+	TargetPolicy *TargetPolicy
+
+	// variables belong to the branch of the dependency graph being run; see withVariables.
 	variables map[string]interface{}
+	slots     chan struct{}
+	client    *resty.Client
 }
 
 func NewGrader(baseUrl string, userID uint) *Grader {
 	return &Grader{
-		BaseUrl:   baseUrl,
-		UserID:    userID,
-		variables: make(map[string]interface{}),
+		BaseUrl:     baseUrl,
+		UserID:      userID,
+		Concurrency: 1,
+		variables:   make(map[string]interface{}),
+		client:      resty.New(),
 	}
 }
 
-// GradeProject is the main entry point for grading a project.
-func (gd *Grader) GradeProject(db *gorm.DB, projID uint) (*ProjectResult, error) {
-	var proj Project
-	if err := db.Find(&proj, projID).Error; err != nil {
-		return nil, fmt.Errorf("project not found: %w", err)
+// withVariables returns a copy of the grader that runs a branch of the dependency graph
+// with its own variables, so that concurrent branches can't see each other's captures.
+func (gd *Grader) withVariables(variables map[string]interface{}) *Grader {
+	branch := *gd
+	branch.variables = variables
+	return &branch
+}
+
+// mergeVariables combines the variables of several branches into a new map, later ones winning.
+func mergeVariables(scopes ...map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, scope := range scopes {
+		for k, v := range scope {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// GradeProject is the main entry point for grading a project. Once ctx is done,
+// whatever hasn't run yet is recorded as cancelled.
+func (gd *Grader) GradeProject(ctx context.Context, db *gorm.DB, projID uint) (*ProjectResult, error) {
+	plan, err := loadPlan(db, projID)
+	if err != nil {
+		return nil, err
 	}
 
+	projectResult, err := gd.gradePlan(ctx, NewGormSink(db), plan)
+	if err != nil {
+		return projectResult, err
+	}
+	db.Preload("Sections").First(projectResult, projectResult.ID)
+	return projectResult, nil
+}
+
+// GradeSuite grades a suite without a database, writing the results to sink.
+func (gd *Grader) GradeSuite(ctx context.Context, suite *Suite, sink ResultSink) (*ProjectResult, error) {
+	plan, err := planSuite(suite)
+	if err != nil {
+		return nil, err
+	}
+	return gd.gradePlan(ctx, sink, plan)
+}
+
+// gradePlan creates the result of a plan and grades it.
+func (gd *Grader) gradePlan(ctx context.Context, sink ResultSink, plan *executionPlan) (*ProjectResult, error) {
 	projectResult := &ProjectResult{
-		ProjectID:   proj.ID,
-		ProjectName: proj.Name,
+		ProjectID:   plan.Project.ID,
+		ProjectName: plan.Project.Name,
 		UserID:      gd.UserID,
 		Status:      StatusProcessing,
 		Message:     "Processing...",
 	}
 
-	if err := db.Create(projectResult).Error; err != nil {
+	if err := sink.CreateProjectResult(projectResult); err != nil {
 		return nil, fmt.Errorf("failed to create initial project result: %w", err)
 	}
 
-	if err := gd.processProject(db, proj, projectResult.ID); err != nil {
-		projectResult.Message = fmt.Sprintf("Error processing project: %s", err.Error())
+	return gd.grade(ctx, sink, plan, projectResult)
+}
+
+// GradeProjectResult grades the project of a result that was created ahead of time,
+// e.g. when the grading was queued.
+func (gd *Grader) GradeProjectResult(ctx context.Context, db *gorm.DB, projectResult *ProjectResult) (*ProjectResult, error) {
+	sink := NewGormSink(db)
+	plan, err := loadPlan(db, projectResult.ProjectID)
+	if err != nil {
 		projectResult.Status = StatusFailed
+		projectResult.Message = fmt.Sprintf("Error loading project: %s", err.Error())
+		if saveErr := sink.SaveProjectResult(projectResult); saveErr != nil {
+			return projectResult, fmt.Errorf("failed to save final project result: %w", saveErr)
+		}
+		return projectResult, err
 	}
 
-	gd.updateProjectResultStatus(db, projectResult)
-
-	if err := db.Save(projectResult).Error; err != nil {
-		return projectResult, fmt.Errorf("failed to save final project result: %w", err)
+	projectResult.ProjectName = plan.Project.Name
+	projectResult.Status = StatusProcessing
+	projectResult.Message = "Processing..."
+	if err := sink.SaveProjectResult(projectResult); err != nil {
+		return projectResult, fmt.Errorf("failed to start project result: %w", err)
 	}
 
+	if _, err := gd.grade(ctx, sink, plan, projectResult); err != nil {
+		return projectResult, err
+	}
 	db.Preload("Sections").First(projectResult, projectResult.ID)
 	return projectResult, nil
 }
 
-// processProject iterates through the sections of a project and processes them.
-func (gd *Grader) processProject(db *gorm.DB, proj Project, projectResultID uint) error {
-	var secs []Section
-	if err := db.Model(&proj).Where("depends_on_id IS NULL").Association("Sections").Find(&secs); err != nil {
-		return fmt.Errorf("failed to load sections for project %d: %w", proj.ID, err)
+// grade runs the plan and stores the final status in projectResult.
+func (gd *Grader) grade(ctx context.Context, sink ResultSink, plan *executionPlan, projectResult *ProjectResult) (*ProjectResult, error) {
+	gd.slots = make(chan struct{}, max(gd.Concurrency, 1))
+	if gd.TargetPolicy != nil {
+		gd.TargetPolicy.apply(gd.client)
+	}
+	if gd.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, gd.RunTimeout)
+		defer cancel()
 	}
 
-	for i := 0; i < len(secs); i++ {
-		sec := secs[i]
-		isPass, err := gd.processSingleSection(db, sec, projectResultID)
+	sections := &rollup{id: projectResult.ID}
+	if err := gd.processProject(ctx, sink, plan, sections); err != nil {
+		projectResult.Message = fmt.Sprintf("Error processing project: %s", err.Error())
+		projectResult.Status = StatusFailed
+	}
+
+	gd.updateProjectResultStatus(plan, projectResult, sections)
+
+	if err := sink.SaveProjectResult(projectResult); err != nil {
+		return projectResult, fmt.Errorf("failed to save final project result: %w", err)
+	}
+	return projectResult, nil
+}
+
+// processProject runs the sections of a project in dependency order, skipping
+// the ones whose prerequisites didn't pass. Sections that don't depend on each
+// other run concurrently; a section starts with the variables of its prerequisites.
+func (gd *Grader) processProject(ctx context.Context, sink ResultSink, plan *executionPlan, parent *rollup) error {
+	scheduleParallel(plan.Sections, func(sec *sectionPlan, inputs []map[string]interface{}) (map[string]interface{}, bool) {
+		if ctx.Err() != nil {
+			if err := gd.skipSection(sink, sec, parent, StatusCancelled, cancelReason(ctx)); err != nil {
+				fmt.Printf("Error cancelling section %d: %v\n", sec.Section.ID, err)
+			}
+			return nil, false
+		}
+
+		branch := gd.withVariables(mergeVariables(inputs...))
+		isPass, err := branch.processSingleSection(ctx, sink, sec, parent)
 		if err != nil {
 			// Log the error but continue processing other sections
-			fmt.Printf("Error processing section %d: %v\n", sec.ID, err)
-		} else if isPass {
-			var dependeds []Section
-			if err := db.Model(&sec).Association("DependsOns").Find(&dependeds); err != nil {
-				fmt.Printf("Error processing depended section %d: %v\n", sec.ID, err)
-			} else {
-				secs = append(secs, dependeds...)
-			}
+			fmt.Printf("Error processing section %d: %v\n", sec.Section.ID, err)
 		}
-	}
+		return branch.variables, isPass
+	}, func(sec, blocker *sectionPlan) {
+		status, reason := StatusSkipped, fmt.Sprintf("Skipped: depends on failed section '%s'.", blocker.Section.Name)
+		if ctx.Err() != nil {
+			status, reason = StatusCancelled, cancelReason(ctx)
+		}
+		if err := gd.skipSection(sink, sec, parent, status, reason); err != nil {
+			fmt.Printf("Error skipping section %d: %v\n", sec.Section.ID, err)
+		}
+	})
 	return nil
 }
 
 // processSingleSection handles the grading of a single section.
-func (gd *Grader) processSingleSection(db *gorm.DB, sec Section, projectResultID uint) (bool, error) {
-	gd.variables = make(map[string]interface{}) // Reset variables for each section
+func (gd *Grader) processSingleSection(ctx context.Context, sink ResultSink, sec *sectionPlan, parent *rollup) (bool, error) {
 	sectionResult := &SectionResult{
-		SectionID:       sec.ID,
-		SectionName:     sec.Name,
-		ProjectResultID: projectResultID,
+		SectionID:       sec.Section.ID,
+		SectionName:     sec.Section.Name,
+		ProjectResultID: parent.id,
 		Status:          StatusProcessing,
 		Message:         "Processing...",
 	}
-	if err := db.Create(sectionResult).Error; err != nil {
+	if err := sink.CreateSectionResult(sectionResult); err != nil {
 		return false, fmt.Errorf("failed to create initial section result: %w", err)
 	}
 
-	if err := gd.processSection(db, sec, sectionResult.ID); err != nil {
+	scenarios := &rollup{id: sectionResult.ID}
+	if err := gd.processSection(ctx, sink, sec, scenarios); err != nil {
 		sectionResult.Message = fmt.Sprintf("Error processing section: %s", err.Error())
 		sectionResult.Status = StatusFailed
 	}
 
-	gd.updateSectionResultStatus(db, sectionResult)
+	gd.updateSectionResultStatus(sec, sectionResult, scenarios)
+	parent.add(sectionResult.Status, sectionResult.Score)
 
-	return sectionResult.Status == StatusPassed, db.Save(sectionResult).Error
+	return sectionResult.Status == StatusPassed, sink.SaveSectionResult(sectionResult)
 }
 
-// processSection iterates through the scenarios of a section and processes them.
-func (gd *Grader) processSection(db *gorm.DB, sec Section, sectionResultID uint) error {
-	var scns []Scenario
-	if err := db.Model(&sec).Where("depends_on_id IS NULL").Association("Scenarios").Find(&scns); err != nil {
-		return fmt.Errorf("failed to load scenarios for section %d: %w", sec.ID, err)
-	}
+// processSection runs the scenarios of a section in dependency order, skipping
+// the ones whose prerequisites didn't pass. Scenarios that don't depend on each
+// other run concurrently, each starting with the section's variables plus the ones
+// captured by its prerequisites. Afterwards the grader holds every scenario's captures.
+func (gd *Grader) processSection(ctx context.Context, sink ResultSink, sec *sectionPlan, parent *rollup) error {
+	outputs := scheduleParallel(sec.Scenarios, func(scn *scenarioPlan, inputs []map[string]interface{}) (map[string]interface{}, bool) {
+		if ctx.Err() != nil {
+			if err := gd.skipScenario(sink, scn, parent, StatusCancelled, cancelReason(ctx)); err != nil {
+				fmt.Printf("Error cancelling scenario %d: %v\n", scn.Scenario.ID, err)
+			}
+			return nil, false
+		}
 
-	for i := 0; i < len(scns); i++ {
-		scn := scns[i]
-		isPass, err := gd.processSingleScenario(db, scn, sectionResultID)
+		branch := gd.withVariables(mergeVariables(append([]map[string]interface{}{gd.variables}, inputs...)...))
+		isPass, err := branch.processSingleScenario(ctx, sink, scn, parent)
 		if err != nil {
-			fmt.Printf("Error processing scenario %d: %v\n", scn.ID, err)
-		} else if isPass {
-			var dependeds []Scenario
-			if err := db.Model(&scn).Association("DependsOns").Find(&dependeds); err != nil {
-				fmt.Printf("Error processing depended scenario %d: %v\n", scn.ID, err)
-			} else {
-				scns = append(scns, dependeds...)
-			}
+			fmt.Printf("Error processing scenario %d: %v\n", scn.Scenario.ID, err)
+		}
+		return branch.variables, isPass
+	}, func(scn, blocker *scenarioPlan) {
+		status, reason := StatusSkipped, fmt.Sprintf("Skipped: depends on failed scenario '%s'.", blocker.Scenario.Name)
+		if ctx.Err() != nil {
+			status, reason = StatusCancelled, cancelReason(ctx)
+		}
+		if err := gd.skipScenario(sink, scn, parent, status, reason); err != nil {
+			fmt.Printf("Error skipping scenario %d: %v\n", scn.Scenario.ID, err)
+		}
+	})
+
+	scopes := []map[string]interface{}{gd.variables}
+	for _, scn := range sec.Scenarios {
+		if output, ok := outputs[scn.Scenario.ID]; ok {
+			scopes = append(scopes, output)
 		}
 	}
+	gd.variables = mergeVariables(scopes...)
 	return nil
 }
 
 // processSingleScenario handles the grading of a single scenario.
-func (gd *Grader) processSingleScenario(db *gorm.DB, scn Scenario, sectionResultID uint) (bool, error) {
+func (gd *Grader) processSingleScenario(ctx context.Context, sink ResultSink, scn *scenarioPlan, parent *rollup) (bool, error) {
 	scenarioResult := &ScenarioResult{
-		ScenarioID:      scn.ID,
-		ScenarioName:    scn.Name,
-		SectionResultID: sectionResultID,
+		ScenarioID:      scn.Scenario.ID,
+		ScenarioName:    scn.Scenario.Name,
+		SectionResultID: parent.id,
 		Status:          StatusProcessing,
 		Message:         "Processing...",
 	}
-	if err := db.Create(scenarioResult).Error; err != nil {
+	if err := sink.CreateScenarioResult(scenarioResult); err != nil {
 		return false, fmt.Errorf("failed to create initial scenario result: %w", err)
 	}
 
-	if err := gd.processScenario(db, scn, scenarioResult.ID); err != nil {
+	// Take one of the run's slots for the scenario's requests
+	tests := &rollup{id: scenarioResult.ID}
+	var err error
+	select {
+	case gd.slots <- struct{}{}:
+		err = gd.processScenario(ctx, sink, scn, tests)
+		<-gd.slots
+	case <-ctx.Done():
+		err = gd.skipTests(sink, scn.Tests, tests, StatusCancelled, cancelReason(ctx))
+	}
+
+	if err != nil {
 		scenarioResult.Message = fmt.Sprintf("Error processing scenario: %s", err.Error())
 		scenarioResult.Status = StatusFailed
 	}
 
-	gd.updateScenarioResultStatus(db, scenarioResult)
+	gd.updateScenarioResultStatus(scn, scenarioResult, tests)
+	parent.add(scenarioResult.Status, scenarioResult.Score)
 
-	return scenarioResult.Status == StatusPassed, db.Save(scenarioResult).Error
+	return scenarioResult.Status == StatusPassed, sink.SaveScenarioResult(scenarioResult)
 }
 
-// processScenario iterates through the tests of a scenario and processes them.
-func (gd *Grader) processScenario(db *gorm.DB, scn Scenario, scenarioResultID uint) error {
-	var tests []Test
-	if err := db.Model(&scn).Where("depends_on_id IS NULL").Association("Tests").Find(&tests); err != nil {
-		return fmt.Errorf("failed to load tests for scenario %d: %w", scn.ID, err)
-	}
+// processScenario runs the tests of a scenario in dependency order, skipping
+// the ones whose prerequisites didn't pass.
+func (gd *Grader) processScenario(ctx context.Context, sink ResultSink, scn *scenarioPlan, parent *rollup) error {
+	schedule(scn.Tests, func(test Test) bool {
+		if ctx.Err() != nil {
+			if err := gd.skipTests(sink, []Test{test}, parent, StatusCancelled, cancelReason(ctx)); err != nil {
+				fmt.Printf("Error cancelling test %d: %v\n", test.ID, err)
+			}
+			return false
+		}
 
-	for i := 0; i < len(tests); i++ {
-		test := tests[i]
-		isPass, err := gd.processSingleTest(db, test, scenarioResultID)
+		isPass, err := gd.processSingleTest(ctx, sink, test, parent)
 		if err != nil {
 			fmt.Printf("Error processing test %d: %v\n", test.ID, err)
-		} else if isPass {
-			var dependeds []Test
-			if err := db.Model(&test).Association("DependsOns").Find(&dependeds); err != nil {
-				fmt.Printf("Error processing depended test %d: %v\n", test.ID, err)
-			} else {
-				tests = append(tests, dependeds...)
-			}
 		}
-	}
+		return isPass
+	}, func(test, blocker Test) {
+		status, reason := StatusSkipped, fmt.Sprintf("Skipped: depends on failed test '%s'.", blocker.Name)
+		if ctx.Err() != nil {
+			status, reason = StatusCancelled, cancelReason(ctx)
+		}
+		if err := gd.skipTests(sink, []Test{test}, parent, status, reason); err != nil {
+			fmt.Printf("Error skipping test %d: %v\n", test.ID, err)
+		}
+	})
 	return nil
 }
 
 // processSingleTest handles the grading of a single test.
-func (gd *Grader) processSingleTest(db *gorm.DB, test Test, scenarioResultID uint) (bool, error) {
+func (gd *Grader) processSingleTest(ctx context.Context, sink ResultSink, test Test, parent *rollup) (bool, error) {
 	testResult := &TestResult{
 		TestID:               test.ID,
 		TestName:             test.Name,
-		ScenarioResultID:     scenarioResultID,
+		ScenarioResultID:     parent.id,
 		ExpectedStatusCode:   test.Response.StatusCode,
 		ExpectedResponseBody: test.Response.ResBody,
+		MaxScore:             test.Points,
 		Status:               StatusProcessing,
 		Message:              "Processing...",
 	}
-	if err := db.Create(testResult).Error; err != nil {
+	if err := sink.CreateTestResult(testResult); err != nil {
 		return false, fmt.Errorf("failed to create initial test result: %w", err)
 	}
 
-	gd.executeTest(db, test, testResult)
+	gd.executeTest(ctx, test, testResult)
+	if testResult.Status == StatusPassed {
+		testResult.Score = test.Points
+	}
+	parent.add(testResult.Status, testResult.Score)
+
+	return testResult.Status == StatusPassed, sink.SaveTestResult(testResult)
+}
 
-	return testResult.Status == StatusPassed, db.Save(testResult).Error
+// skipSection records a section that won't run, along with everything in it, with the given
+// status (skipped or cancelled) and reason.
+func (gd *Grader) skipSection(sink ResultSink, sec *sectionPlan, parent *rollup, status GradingStatus, reason string) error {
+	sectionResult := &SectionResult{
+		SectionID:       sec.Section.ID,
+		SectionName:     sec.Section.Name,
+		ProjectResultID: parent.id,
+		Status:          status,
+		Message:         reason,
+		TotalScenarios:  uint(len(sec.Scenarios)),
+		MaxScore:        sec.Section.Points,
+	}
+	if err := sink.CreateSectionResult(sectionResult); err != nil {
+		return fmt.Errorf("failed to create %s section result: %w", status, err)
+	}
+
+	parent.add(status, 0)
+
+	scenarios := &rollup{id: sectionResult.ID}
+	for _, scn := range sec.Scenarios {
+		if err := gd.skipScenario(sink, scn, scenarios, status, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipScenario records a scenario that won't run, along with its tests, with the given status and reason.
+func (gd *Grader) skipScenario(sink ResultSink, scn *scenarioPlan, parent *rollup, status GradingStatus, reason string) error {
+	scenarioResult := &ScenarioResult{
+		ScenarioID:      scn.Scenario.ID,
+		ScenarioName:    scn.Scenario.Name,
+		SectionResultID: parent.id,
+		Status:          status,
+		Message:         reason,
+		MaxScore:        scn.Scenario.Points,
+	}
+	if err := sink.CreateScenarioResult(scenarioResult); err != nil {
+		return fmt.Errorf("failed to create %s scenario result: %w", status, err)
+	}
+
+	parent.add(status, 0)
+
+	return gd.skipTests(sink, scn.Tests, &rollup{id: scenarioResult.ID}, status, reason)
+}
+
+// skipTests records tests that won't run with the given status and reason.
+func (gd *Grader) skipTests(sink ResultSink, tests []Test, parent *rollup, status GradingStatus, reason string) error {
+	if len(tests) == 0 {
+		return nil
+	}
+
+	testResults := make([]TestResult, len(tests))
+	for i, test := range tests {
+		testResults[i] = TestResult{
+			TestID:               test.ID,
+			TestName:             test.Name,
+			ScenarioResultID:     parent.id,
+			ExpectedStatusCode:   test.Response.StatusCode,
+			ExpectedResponseBody: test.Response.ResBody,
+			MaxScore:             test.Points,
+			Status:               status,
+			Message:              reason,
+		}
+	}
+	if err := sink.CreateTestResults(testResults); err != nil {
+		return fmt.Errorf("failed to create %s test results: %w", status, err)
+	}
+	for range testResults {
+		parent.add(status, 0)
+	}
+	return nil
+}
+
+// cancelReason explains why a run whose ctx is done stopped early.
+func cancelReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "Cancelled: grading ran out of time."
+	}
+	return "Cancelled: grading was cancelled."
 }
 
 // executeTest runs a single test and updates its result.
-func (gd *Grader) executeTest(db *gorm.DB, test Test, testResult *TestResult) {
-	resp, err := gd.makeRequest(test)
-	if err != nil {
+func (gd *Grader) executeTest(ctx context.Context, test Test, testResult *TestResult) {
+	testCtx := ctx
+	if gd.TestTimeout > 0 {
+		var cancel context.CancelFunc
+		testCtx, cancel = context.WithTimeout(ctx, gd.TestTimeout)
+		defer cancel()
+	}
+
+	resp, err := gd.makeRequest(testCtx, test)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		testResult.Status = StatusCancelled
+		testResult.Message = cancelReason(ctx)
+		return
+	case err != nil && errors.Is(testCtx.Err(), context.DeadlineExceeded):
 		testResult.Status = StatusFailed
-		testResult.Message = fmt.Sprintf("request failed: %w", err)
+		testResult.Reason = ReasonTimeout
+		testResult.Message = fmt.Sprintf("request timed out after %s", gd.TestTimeout)
+		return
+	case errors.Is(err, ErrTargetBlocked):
+		testResult.Status = StatusFailed
+		testResult.Reason = ReasonTargetBlocked
+		testResult.Message = fmt.Sprintf("request refused by the target policy: %v", err)
+		return
+	case err != nil:
+		testResult.Status = StatusFailed
+		testResult.Reason = ReasonRequestFailed
+		testResult.Message = fmt.Sprintf("request failed: %v", err)
 		return
 	}
 
 	testResult.ActualStatusCode = uint(resp.StatusCode())
 	testResult.ActualResponseBody = resp.String()
 
-	if err := gd.validateResponse(test, resp); err != nil {
+	err = gd.validateResponse(test, resp)
+	failed := gd.checkAssertions(test, resp, testResult)
+	switch {
+	case err != nil:
 		testResult.Status = StatusFailed
+		testResult.Reason = ReasonMismatch
 		testResult.Message = err.Error()
-	} else {
+	case failed > 0:
+		testResult.Status = StatusFailed
+		testResult.Reason = ReasonMismatch
+		testResult.Message = fmt.Sprintf("%d/%d assertions failed", failed, len(testResult.Assertions))
+	default:
 		testResult.Status = StatusPassed
 		testResult.Message = "Test passed successfully!"
 	}
 }
 
-// makeRequest executes the HTTP request for a test.
-func (gd *Grader) makeRequest(test Test) (*resty.Response, error) {
-	client := resty.New()
+// checkAssertions evaluates every assertion of a test, recording each result, and
+// returns how many failed. They run after validateResponse so they can use its captures.
+func (gd *Grader) checkAssertions(test Test, resp *resty.Response, testResult *TestResult) int {
+	if len(test.Response.Assertions) == 0 {
+		return 0
+	}
 
+	var body interface{}
+	bodyErr := json.Unmarshal(resp.Body(), &body)
+	failed := 0
+	for _, a := range test.Response.Assertions {
+		result := gd.checkAssertion(a.Expr, body, bodyErr)
+		if !result.Passed {
+			failed++
+		}
+		testResult.Assertions = append(testResult.Assertions, result)
+	}
+	return failed
+}
+
+// makeRequest executes the HTTP request for a test, giving up once ctx is done.
+func (gd *Grader) makeRequest(ctx context.Context, test Test) (*resty.Response, error) {
 	// Substitute variables in the URL, headers, and body
 	fullURL := gd.substituteVariables(gd.BaseUrl + test.Request.Url)
-	req := client.R()
+	req := gd.client.R().SetContext(ctx)
 
 	for _, header := range test.Request.Headers {
 		req.SetHeader(gd.substituteVariables(header.Key), gd.substituteVariables(header.Value))
@@ -261,21 +536,24 @@ func (gd *Grader) validateResponse(test Test, resp *resty.Response) error {
 		return fmt.Errorf("status code mismatch: expected %d, got %d", test.Response.StatusCode, resp.StatusCode())
 	}
 
-	// TODO
-	// Process headers for variables
-	// for _, header := range test.Response.Headers {
-	// 	gd.processHeaderForVariables(header, resp.Header())
-	// }
+	if err := gd.validateHeaders(test.Response.Headers, resp.Header()); err != nil {
+		return err
+	}
 
 	if test.Response.ResBody != "" {
-		var actualBody, expectedBody map[string]interface{}
+		// The body may be any JSON value, not just an object.
+		var actualBody, expectedBody interface{}
 		if err := json.Unmarshal(resp.Body(), &actualBody); err != nil {
 			return fmt.Errorf("failed to unmarshal actual response body: %w", err)
 		}
 		if err := json.Unmarshal([]byte(test.Response.ResBody), &expectedBody); err != nil {
 			return fmt.Errorf("failed to unmarshal expected response body: %w", err)
 		}
-		r, err := gd.compareJSON(actualBody, expectedBody)
+		cmp, err := newComparison(test.Response)
+		if err != nil {
+			return err
+		}
+		r, err := gd.jsonValueEquals(actualBody, expectedBody, cmp)
 		if !r {
 			return err
 		}
@@ -283,80 +561,138 @@ func (gd *Grader) validateResponse(test Test, resp *resty.Response) error {
 	return nil
 }
 
-// updateProjectResultStatus updates the final status of a project result based on its sections.
-func (gd *Grader) updateProjectResultStatus(db *gorm.DB, projectResult *ProjectResult) {
-	var failedSections int64
-	db.Model(&SectionResult{}).Where("project_result_id = ? AND status = ?", projectResult.ID, StatusFailed).Count(&failedSections)
+// Scores roll up bottom-up. A test earns its Points when it passes and nothing otherwise.
+// A scenario or section earns its own Points scaled by the share of its children's points
+// that were earned, so a scenario with half of its test points passed earns half of its
+// Points. A project's score is the sum of its sections' scores, out of the sum of their
+// Points. Children that never ran still count towards the share through the plan.
+
+// rollup counts the results recorded under a parent result by status and adds up their
+// scores as they come in, so that the parent's status is worked out without reading
+// its children back. Children that run concurrently add to it at the same time.
+type rollup struct {
+	id uint // of the parent result
+
+	mu        sync.Mutex
+	total     int
+	passed    int
+	failed    int
+	skipped   int
+	cancelled int
+	score     float64
+}
 
-	if failedSections == 0 {
-		projectResult.Status = StatusPassed
-		projectResult.Message = "All sections passed."
-	} else {
-		projectResult.Status = StatusFailed
-		projectResult.Message = "Some sections failed."
+func (r *rollup) add(status GradingStatus, score float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total++
+	r.score += score
+	switch status {
+	case StatusPassed:
+		r.passed++
+	case StatusFailed:
+		r.failed++
+	case StatusSkipped:
+		r.skipped++
+	case StatusCancelled:
+		r.cancelled++
 	}
 }
 
-// updateSectionResultStatus updates the final status of a section result based on its scenarios.
-func (gd *Grader) updateSectionResultStatus(db *gorm.DB, sectionResult *SectionResult) {
-	var totalScenarios, passedScenarios int64
-	db.Model(&ScenarioResult{}).Where("section_result_id = ?", sectionResult.ID).Count(&totalScenarios)
-	db.Model(&ScenarioResult{}).Where("section_result_id = ? AND status = ?", sectionResult.ID, StatusPassed).Count(&passedScenarios)
+// earnedScore scales points by the share of maxChildren that was earned.
+func earnedScore(points, earned, maxChildren float64) float64 {
+	if maxChildren <= 0 {
+		return 0
+	}
+	return points * earned / maxChildren
+}
 
-	sectionResult.TotalScenarios = uint(totalScenarios)
-	sectionResult.PassedScenarios = uint(passedScenarios)
+// updateProjectResultStatus updates the final status and score of a project result based on its sections.
+func (gd *Grader) updateProjectResultStatus(plan *executionPlan, projectResult *ProjectResult, sections *rollup) {
+	projectResult.MaxScore = plan.maxScore()
+	projectResult.Score = sections.score
 
-	if totalScenarios > 0 {
-		sectionResult.Score = (float64(passedScenarios) / float64(totalScenarios)) * 100
+	if sections.cancelled > 0 {
+		projectResult.Status = StatusCancelled
+		projectResult.Message = fmt.Sprintf("Cancelled before all sections finished. Score: %.2f/%.2f", projectResult.Score, projectResult.MaxScore)
+	} else if sections.passed == sections.total {
+		projectResult.Status = StatusPassed
+		projectResult.Message = fmt.Sprintf("All sections passed. Score: %.2f/%.2f", projectResult.Score, projectResult.MaxScore)
 	} else {
-		sectionResult.Score = 100 // Or 0, depending on desired behavior for no scenarios
+		projectResult.Status = StatusFailed
+		projectResult.Message = fmt.Sprintf("Some sections failed. Score: %.2f/%.2f", projectResult.Score, projectResult.MaxScore)
 	}
+}
+
+// updateSectionResultStatus updates the final status and score of a section result based on its scenarios.
+func (gd *Grader) updateSectionResultStatus(sec *sectionPlan, sectionResult *SectionResult, scenarios *rollup) {
+	sectionResult.TotalScenarios = uint(scenarios.total)
+	sectionResult.PassedScenarios = uint(scenarios.passed)
+
+	sectionResult.MaxScore = sec.Section.Points
+	sectionResult.Score = earnedScore(sec.Section.Points, scenarios.score, sec.maxScore())
 
-	if passedScenarios == totalScenarios {
+	if scenarios.cancelled > 0 {
+		sectionResult.Status = StatusCancelled
+		sectionResult.Message = fmt.Sprintf("%d/%d scenarios cancelled. Score: %.2f/%.2f", scenarios.cancelled, scenarios.total, sectionResult.Score, sectionResult.MaxScore)
+	} else if scenarios.passed == scenarios.total {
 		sectionResult.Status = StatusPassed
-		sectionResult.Message = fmt.Sprintf("All %d scenarios passed. Score: %.2f%%", totalScenarios, sectionResult.Score)
+		sectionResult.Message = fmt.Sprintf("All %d scenarios passed. Score: %.2f/%.2f", scenarios.total, sectionResult.Score, sectionResult.MaxScore)
 	} else {
 		sectionResult.Status = StatusFailed
-		sectionResult.Message = fmt.Sprintf("%d/%d scenarios passed. Score: %.2f%%", passedScenarios, totalScenarios, sectionResult.Score)
+		sectionResult.Message = fmt.Sprintf("%d/%d scenarios passed. Score: %.2f/%.2f", scenarios.passed, scenarios.total, sectionResult.Score, sectionResult.MaxScore)
 	}
 }
 
-// updateScenarioResultStatus updates the final status of a scenario result based on its tests.
-func (gd *Grader) updateScenarioResultStatus(db *gorm.DB, scenarioResult *ScenarioResult) {
-	var totalTests, failedTests int64
-	db.Model(&TestResult{}).Where("scenario_result_id = ?", scenarioResult.ID).Count(&totalTests)
-	db.Model(&TestResult{}).Where("scenario_result_id = ? AND status = ?", scenarioResult.ID, StatusFailed).Count(&failedTests)
+// updateScenarioResultStatus updates the final status and score of a scenario result based on its tests.
+func (gd *Grader) updateScenarioResultStatus(scn *scenarioPlan, scenarioResult *ScenarioResult, tests *rollup) {
+	scenarioResult.MaxScore = scn.Scenario.Points
+	scenarioResult.Score = earnedScore(scn.Scenario.Points, tests.score, scn.maxScore())
 
-	if failedTests == 0 {
+	if tests.cancelled > 0 {
+		scenarioResult.Status = StatusCancelled
+		scenarioResult.Message = fmt.Sprintf("%d/%d tests cancelled. Score: %.2f/%.2f", tests.cancelled, tests.total, scenarioResult.Score, scenarioResult.MaxScore)
+	} else if tests.failed == 0 && tests.skipped == 0 {
 		scenarioResult.Status = StatusPassed
-		scenarioResult.Message = "All tests passed."
+		scenarioResult.Message = fmt.Sprintf("All tests passed. Score: %.2f/%.2f", scenarioResult.Score, scenarioResult.MaxScore)
 	} else {
 		scenarioResult.Status = StatusFailed
-		scenarioResult.Message = fmt.Sprintf("%d/%d tests failed.", failedTests, totalTests)
+		scenarioResult.Message = fmt.Sprintf("%d/%d tests failed, %d skipped. Score: %.2f/%.2f", tests.failed, tests.total, tests.skipped, scenarioResult.Score, scenarioResult.MaxScore)
 	}
 }
 
-// compareJSON compares two JSON objects (maps) field by field.
-func (gd *Grader) compareJSON(actual, expected map[string]interface{}) (bool, error) {
+// compareJSON compares two JSON objects (maps) field by field. In strict mode the
+// actual object may not have keys the expected one doesn't.
+func (gd *Grader) compareJSON(actual, expected map[string]interface{}, cmp comparison) (bool, error) {
+	expectedKeys := make(map[string]bool, len(expected))
 	for key, expectedVal := range expected {
 		// Handle variable substitution in expected keys
 		substitutedKey := gd.substituteVariables(key)
+		expectedKeys[substitutedKey] = true
 		actualVal, ok := actual[substitutedKey]
 		if !ok {
 			return false, fmt.Errorf("key '%s' not found in actual response", substitutedKey)
 		}
 
 		// Compare values recursively
-		r, err := gd.jsonValueEquals(actualVal, expectedVal)
+		r, err := gd.jsonValueEquals(actualVal, expectedVal, cmp.at(&pathStep{Key: substitutedKey}))
 		if !r {
 			return false, fmt.Errorf("error at key '%s': %w", substitutedKey, err)
 		}
 	}
+
+	if cmp.objects == ObjectsStrict {
+		for key := range actual {
+			if !expectedKeys[key] {
+				return false, fmt.Errorf("unexpected key '%s' in actual response", key)
+			}
+		}
+	}
 	return true, nil
 }
 
 // jsonValueEquals compares two interface{} values, handling different JSON types recursively.
-func (gd *Grader) jsonValueEquals(val1, val2 interface{}) (bool, error) {
+func (gd *Grader) jsonValueEquals(val1, val2 interface{}, cmp comparison) (bool, error) {
 	if val1 == nil && val2 == nil {
 		return true, nil
 	}
@@ -380,13 +716,13 @@ func (gd *Grader) jsonValueEquals(val1, val2 interface{}) (bool, error) {
 		if !ok {
 			return false, fmt.Errorf("type mismatch: expected a JSON object")
 		}
-		return gd.compareJSON(v1, v2)
+		return gd.compareJSON(v1, v2, cmp)
 	case []interface{}:
 		v2, ok := val2.([]interface{})
 		if !ok {
 			return false, fmt.Errorf("type mismatch: expected a JSON array")
 		}
-		return gd.compareJSONArray(v1, v2)
+		return gd.compareJSONArray(v1, v2, cmp)
 	default:
 		// For primitive types, use fmt.Sprintf for a robust comparison
 		if fmt.Sprintf("%v", val1) != fmt.Sprintf("%v", val2) {
@@ -396,23 +732,32 @@ func (gd *Grader) jsonValueEquals(val1, val2 interface{}) (bool, error) {
 	}
 }
 
-// compareJSONArray compares two JSON arrays, checking if all elements in expected are present in actual, regardless of order.
-func (gd *Grader) compareJSONArray(actual, expected []interface{}) (bool, error) {
-	if len(actual) != len(expected) {
+// compareJSONArray compares two JSON arrays according to the array mode: element by
+// element when ordered, and otherwise by finding each expected element among the actual
+// ones, which may have more of them in contains mode.
+func (gd *Grader) compareJSONArray(actual, expected []interface{}, cmp comparison) (bool, error) {
+	if cmp.arrays != ArraysContains && len(actual) != len(expected) {
 		return false, fmt.Errorf("array length mismatch: expected %d, got %d", len(expected), len(actual))
 	}
 
-	actualCopy := make([]interface{}, len(actual))
-	copy(actualCopy, actual)
+	if cmp.arrays == ArraysOrdered {
+		for i, expectedItem := range expected {
+			if eq, err := gd.jsonValueEquals(actual[i], expectedItem, cmp.at(&pathStep{Index: i})); !eq {
+				return false, fmt.Errorf("error at index %d: %w", i, err)
+			}
+		}
+		return true, nil
+	}
 
+	used := make([]bool, len(actual))
 	for _, expectedItem := range expected {
 		found := false
-		for i, actualItem := range actualCopy {
-			if actualItem == nil {
+		for i, actualItem := range actual {
+			if used[i] {
 				continue
 			}
-			if eq, _ := gd.jsonValueEquals(actualItem, expectedItem); eq {
-				actualCopy[i] = nil // Mark as used
+			if eq, _ := gd.jsonValueEquals(actualItem, expectedItem, cmp.at(&pathStep{Index: i})); eq {
+				used[i] = true
 				found = true
 				break
 			}
@@ -435,34 +780,68 @@ func (gd *Grader) substituteVariables(input string) string {
 	})
 }
 
-// processHeaderForVariables extracts variables from response headers.
-func (gd *Grader) processHeaderForVariables(expectedHeader THeader, actualHeaders map[string][]string) {
-	substitutedKey := gd.substituteVariables(expectedHeader.Key)
-
-	// Handle variable capture in value
-	matches := variableRegex.FindStringSubmatch(expectedHeader.Value)
-	if len(matches) > 1 {
-		varName := matches[1]
-		if values, ok := actualHeaders[substitutedKey]; ok && len(values) > 0 {
-			gd.variables[varName] = values[0]
+// validateHeaders checks the expected response headers against the actual ones,
+// capturing any $<var_name> placeholders. All mismatches are reported together.
+func (gd *Grader) validateHeaders(expected []TResHeader, actual http.Header) error {
+	var mismatches []string
+	for _, header := range expected {
+		if err := gd.processHeaderForVariables(header, actual); err != nil {
+			mismatches = append(mismatches, err.Error())
 		}
-		return
 	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("header mismatch: %s", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
 
-	// Standard header validation after substitution
-	substitutedValue := gd.substituteVariables(expectedHeader.Value)
-	if values, ok := actualHeaders[substitutedKey]; ok {
-		found := false
-		for _, v := range values {
-			if v == substitutedValue {
-				found = true
-				break
+// processHeaderForVariables matches an expected header against the actual values
+// of that header and extracts variables from the first value that matches. A
+// Content-Type also matches by its media type alone, so that "application/json"
+// matches "application/json; charset=utf-8".
+func (gd *Grader) processHeaderForVariables(expectedHeader TResHeader, actualHeaders http.Header) error {
+	substitutedKey := gd.substituteVariables(expectedHeader.Key)
+	values := actualHeaders.Values(substitutedKey)
+	if len(values) == 0 {
+		return fmt.Errorf("header '%s' not found in actual response", substitutedKey)
+	}
+
+	pattern := gd.headerPattern(expectedHeader.Value)
+	for _, v := range values {
+		matches := pattern.FindStringSubmatch(v)
+		if matches == nil && http.CanonicalHeaderKey(substitutedKey) == "Content-Type" {
+			if mediaType, _, err := mime.ParseMediaType(v); err == nil {
+				matches = pattern.FindStringSubmatch(mediaType)
 			}
 		}
-		if !found {
-			// Optional: Log or handle header mismatch
+		if matches == nil {
+			continue
 		}
-	} else {
-		// Optional: Log or handle missing header
+		for i, varName := range pattern.SubexpNames() {
+			if varName != "" {
+				gd.variables[varName] = matches[i]
+			}
+		}
+		return nil
 	}
+	return fmt.Errorf("header '%s': expected '%s', got '%s'", substitutedKey, gd.substituteVariables(expectedHeader.Value), strings.Join(values, ", "))
+}
+
+// headerPattern turns an expected header value into an anchored regexp. Known
+// {{var_name}} placeholders are substituted and each $<var_name> becomes a capture group.
+func (gd *Grader) headerPattern(expected string) *regexp.Regexp {
+	expected = gd.substituteVariables(expected)
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	last := 0
+	for _, loc := range variableRegex.FindAllStringSubmatchIndex(expected, -1) {
+		sb.WriteString(regexp.QuoteMeta(expected[last:loc[0]]))
+		sb.WriteString("(?P<" + expected[loc[2]:loc[3]] + ">.*?)")
+		last = loc[1]
+	}
+	sb.WriteString(regexp.QuoteMeta(expected[last:]))
+	sb.WriteString("$")
+
+	return regexp.MustCompile(sb.String())
 }
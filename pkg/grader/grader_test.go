@@ -0,0 +1,625 @@
+package grader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestService answers like a small users API: POST /users creates user 7, GET
+// /users/7 returns it, /broken fails and /slow answers only once the request is gone.
+func newTestService(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 7, "name": "bob"})
+	})
+	mux.HandleFunc("GET /users/7", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 7, "name": "bob", "tags": []string{"a", "b"}})
+	})
+	mux.HandleFunc("GET /broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func readTestSuite(t *testing.T, src string) *Suite {
+	t.Helper()
+	suite, err := ReadSuite(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("ReadSuite: %v", err)
+	}
+	return suite
+}
+
+// gradeTestSuite grades src with gd and returns the result tree from a MemorySink.
+func gradeTestSuite(t *testing.T, ctx context.Context, gd *Grader, src string) *ProjectResult {
+	t.Helper()
+	sink := NewMemorySink()
+	result, err := gd.GradeSuite(ctx, readTestSuite(t, src), sink)
+	if err != nil {
+		t.Fatalf("GradeSuite: %v", err)
+	}
+	result, err = sink.ProjectResult(result.ID)
+	if err != nil {
+		t.Fatalf("ProjectResult: %v", err)
+	}
+	return result
+}
+
+func findSection(t *testing.T, result *ProjectResult, name string) SectionResult {
+	t.Helper()
+	for _, sec := range result.Sections {
+		if sec.SectionName == name {
+			return sec
+		}
+	}
+	t.Fatalf("no result for section '%s'", name)
+	return SectionResult{}
+}
+
+func findScenario(t *testing.T, sec SectionResult, name string) ScenarioResult {
+	t.Helper()
+	for _, scn := range sec.Scenarios {
+		if scn.ScenarioName == name {
+			return scn
+		}
+	}
+	t.Fatalf("no result for scenario '%s'", name)
+	return ScenarioResult{}
+}
+
+func findTest(t *testing.T, scn ScenarioResult, name string) TestResult {
+	t.Helper()
+	for _, test := range scn.Tests {
+		if test.TestName == name {
+			return test
+		}
+	}
+	t.Fatalf("no result for test '%s'", name)
+	return TestResult{}
+}
+
+func checkScore(t *testing.T, what string, status GradingStatus, score, maxScore float64, wantStatus GradingStatus, wantScore, wantMax float64) {
+	t.Helper()
+	if status != wantStatus {
+		t.Errorf("%s: status %s, want %s", what, status, wantStatus)
+	}
+	if math.Abs(score-wantScore) > 1e-9 || math.Abs(maxScore-wantMax) > 1e-9 {
+		t.Errorf("%s: score %.2f/%.2f, want %.2f/%.2f", what, score, maxScore, wantScore, wantMax)
+	}
+}
+
+const rollupSuite = `
+name: Users API
+sections:
+  - name: Users
+    points: 4
+    scenarios:
+      - name: Create
+        points: 2
+        tests:
+          - name: create
+            request: {method: POST, url: /users, body: {"name": "bob"}}
+            response:
+              status_code: 201
+              body: {"id": "$<user_id>"}
+          - name: get
+            depends_on: [create]
+            request: {method: GET, url: "/users/{{user_id}}"}
+            response:
+              status_code: 200
+              body: {"id": "{{user_id}}", "tags": ["b", "a"]}
+              assertions:
+                - $.tags.length == 2
+      - name: Broken
+        points: 2
+        tests:
+          - name: ok
+            request: {method: GET, url: /users/7}
+            response: {status_code: 200}
+          - name: broken
+            points: 3
+            request: {method: GET, url: /broken}
+            response: {status_code: 200}
+          - name: after broken
+            depends_on: [broken]
+            request: {method: GET, url: /users/7}
+            response: {status_code: 200}
+  - name: Admin
+    depends_on: [Users]
+    scenarios:
+      - name: List
+        tests:
+          - name: list
+            request: {method: GET, url: /users/7}
+            response: {status_code: 200}
+`
+
+func TestGradeSuiteRollup(t *testing.T) {
+	srv := newTestService(t)
+	result := gradeTestSuite(t, context.Background(), NewGrader(srv.URL, 0), rollupSuite)
+
+	users := findSection(t, result, "Users")
+	create := findScenario(t, users, "Create")
+	checkScore(t, "scenario Create", create.Status, create.Score, create.MaxScore, StatusPassed, 2, 2)
+	get := findTest(t, create, "get")
+	checkScore(t, "test get", get.Status, get.Score, get.MaxScore, StatusPassed, 1, 1)
+	if len(get.Assertions) != 1 || !get.Assertions[0].Passed {
+		t.Errorf("test get: assertions %+v, want one that passed", get.Assertions)
+	}
+
+	// 1 of the scenario's 5 test points was earned.
+	broken := findScenario(t, users, "Broken")
+	checkScore(t, "scenario Broken", broken.Status, broken.Score, broken.MaxScore, StatusFailed, 0.4, 2)
+	failed := findTest(t, broken, "broken")
+	if failed.Status != StatusFailed || failed.Reason != ReasonMismatch {
+		t.Errorf("test broken: %s (%s), want failed (%s)", failed.Status, failed.Reason, ReasonMismatch)
+	}
+	after := findTest(t, broken, "after broken")
+	checkScore(t, "test after broken", after.Status, after.Score, after.MaxScore, StatusSkipped, 0, 1)
+
+	checkScore(t, "section Users", users.Status, users.Score, users.MaxScore, StatusFailed, 2.4, 4)
+	if users.TotalScenarios != 2 || users.PassedScenarios != 1 {
+		t.Errorf("section Users: %d/%d scenarios passed, want 1/2", users.PassedScenarios, users.TotalScenarios)
+	}
+
+	admin := findSection(t, result, "Admin")
+	checkScore(t, "section Admin", admin.Status, admin.Score, admin.MaxScore, StatusSkipped, 0, 1)
+	list := findTest(t, findScenario(t, admin, "List"), "list")
+	if list.Status != StatusSkipped || !strings.Contains(list.Message, "'Users'") {
+		t.Errorf("test list: %s %q, want skipped for depending on 'Users'", list.Status, list.Message)
+	}
+
+	checkScore(t, "project", result.Status, result.Score, result.MaxScore, StatusFailed, 2.4, 5)
+}
+
+func TestGradeSuitePassed(t *testing.T) {
+	srv := newTestService(t)
+	result := gradeTestSuite(t, context.Background(), NewGrader(srv.URL, 0), `
+name: Users API
+sections:
+  - name: Users
+    points: 0.5
+    scenarios:
+      - name: Get
+        tests:
+          - name: get
+            request: {method: GET, url: /users/7}
+            response: {status_code: 200, body: {"name": "bob"}}
+`)
+	checkScore(t, "project", result.Status, result.Score, result.MaxScore, StatusPassed, 0.5, 0.5)
+}
+
+const slowSuite = `
+name: Users API
+sections:
+  - name: Users
+    scenarios:
+      - name: Slow
+        tests:
+          - name: slow
+            request: {method: GET, url: /slow}
+            response: {status_code: 200}
+          - name: after slow
+            depends_on: [slow]
+            request: {method: GET, url: /users/7}
+            response: {status_code: 200}
+  - name: Admin
+    depends_on: [Users]
+    scenarios:
+      - name: List
+        tests:
+          - name: list
+            request: {method: GET, url: /users/7}
+            response: {status_code: 200}
+`
+
+func TestGradeSuiteRunTimeout(t *testing.T) {
+	srv := newTestService(t)
+	gd := NewGrader(srv.URL, 0)
+	gd.RunTimeout = 100 * time.Millisecond
+	result := gradeTestSuite(t, context.Background(), gd, slowSuite)
+
+	users := findSection(t, result, "Users")
+	slow := findScenario(t, users, "Slow")
+	for _, name := range []string{"slow", "after slow"} {
+		test := findTest(t, slow, name)
+		if test.Status != StatusCancelled || !strings.Contains(test.Message, "ran out of time") {
+			t.Errorf("test %s: %s %q, want cancelled for running out of time", name, test.Status, test.Message)
+		}
+	}
+	checkScore(t, "scenario Slow", slow.Status, slow.Score, slow.MaxScore, StatusCancelled, 0, 1)
+	if admin := findSection(t, result, "Admin"); admin.Status != StatusCancelled {
+		t.Errorf("section Admin: status %s, want %s", admin.Status, StatusCancelled)
+	}
+	checkScore(t, "project", result.Status, result.Score, result.MaxScore, StatusCancelled, 0, 2)
+}
+
+func TestGradeSuiteCancelled(t *testing.T) {
+	srv := newTestService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := gradeTestSuite(t, ctx, NewGrader(srv.URL, 0), slowSuite)
+
+	for _, sec := range result.Sections {
+		for _, scn := range sec.Scenarios {
+			for _, test := range scn.Tests {
+				if test.Status != StatusCancelled || test.Message != "Cancelled: grading was cancelled." {
+					t.Errorf("test %s: %s %q, want cancelled", test.TestName, test.Status, test.Message)
+				}
+			}
+		}
+	}
+	if len(result.Sections) != 2 {
+		t.Errorf("%d section results, want 2", len(result.Sections))
+	}
+	checkScore(t, "project", result.Status, result.Score, result.MaxScore, StatusCancelled, 0, 2)
+}
+
+func TestProcessHeaderForVariables(t *testing.T) {
+	actual := http.Header{}
+	actual.Set("Content-Type", "application/json; charset=utf-8")
+	actual.Set("Location", "/users/42")
+	actual.Set("X-Request-Id", "abc")
+	actual.Set("X-Version", "1.2")
+	actual.Add("Set-Cookie", "theme=dark; Path=/")
+	actual.Add("Set-Cookie", "session=s3cr3t; Path=/; HttpOnly")
+
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		captured map[string]interface{}
+		err      string // part of the error, if the header shouldn't match
+	}{
+		{name: "exact", key: "Content-Type", value: "application/json; charset=utf-8"},
+		{name: "media type", key: "Content-Type", value: "application/json"},
+		{name: "media type in lower case", key: "content-type", value: "application/json"},
+		{name: "other media type", key: "Content-Type", value: "application/xml", err: "expected 'application/xml'"},
+		{name: "media type prefix", key: "Content-Type", value: "application/js", err: "expected 'application/js'"},
+		{name: "other parameters", key: "Content-Type", value: "application/json; charset=latin1", err: "got 'application/json; charset=utf-8'"},
+		{name: "capture", key: "Location", value: "/users/$<user_id>", captured: map[string]interface{}{"user_id": "42"}},
+		{name: "capture mismatch", key: "Location", value: "/posts/$<post_id>", err: "got '/users/42'"},
+		{
+			name: "second cookie", key: "Set-Cookie", value: "session=$<session>; Path=/; HttpOnly",
+			captured: map[string]interface{}{"session": "s3cr3t"},
+		},
+		{name: "no cookie matches", key: "Set-Cookie", value: "lang=$<lang>; Path=/", err: "got 'theme=dark; Path=/, session=s3cr3t; Path=/; HttpOnly'"},
+		{name: "missing", key: "X-Missing", value: "x", err: "header 'X-Missing' not found"},
+		{name: "key variable", key: "{{id_header}}", value: "{{request_id}}"},
+		{name: "unknown key variable", key: "{{nothing}}", value: "x", err: "header '{{nothing}}' not found"},
+		{name: "quoted", key: "X-Version", value: "1.2"},
+		{name: "quoted mismatch", key: "X-Version", value: "1.", err: "expected '1.'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gd := NewGrader("", 0)
+			gd.variables["id_header"] = "X-Request-Id"
+			gd.variables["request_id"] = "abc"
+			err := gd.processHeaderForVariables(TResHeader{Key: tt.key, Value: tt.value}, actual)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one about %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for name, want := range tt.captured {
+				if got := gd.variables[name]; got != want {
+					t.Errorf("variable %s is %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateHeaders(t *testing.T) {
+	actual := http.Header{}
+	actual.Set("Content-Type", "text/plain")
+	actual.Set("Location", "/users/42")
+
+	gd := NewGrader("", 0)
+	err := gd.validateHeaders([]TResHeader{
+		{Key: "Content-Type", Value: "application/json"},
+		{Key: "Location", Value: "/users/$<user_id>"},
+		{Key: "ETag", Value: "$<etag>"},
+	}, actual)
+	if err == nil {
+		t.Fatal("no error for mismatching headers")
+	}
+	for _, want := range []string{"header mismatch: ", "'Content-Type': expected 'application/json'", "'ETag' not found"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+	// Matching headers still capture when others don't match.
+	if gd.variables["user_id"] != "42" {
+		t.Errorf("user_id is %v, want 42", gd.variables["user_id"])
+	}
+
+	if err := gd.validateHeaders(nil, actual); err != nil {
+		t.Errorf("unexpected error without expected headers: %v", err)
+	}
+}
+
+func dependencyTest(id uint, name string, dependsOn ...uint) Test {
+	test := Test{Name: name, DependsOnIDs: dependsOn}
+	test.ID = id
+	return test
+}
+
+func TestValidateDependencies(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []Test
+		err   error
+		msg   string
+	}{
+		{name: "none", nodes: []Test{dependencyTest(1, "a"), dependencyTest(2, "b")}},
+		{name: "chain", nodes: []Test{dependencyTest(1, "a"), dependencyTest(2, "b", 1), dependencyTest(3, "c", 2)}},
+		{
+			name:  "diamond",
+			nodes: []Test{dependencyTest(1, "a"), dependencyTest(2, "b", 1), dependencyTest(3, "c", 1), dependencyTest(4, "d", 2, 3)},
+		},
+		{
+			name:  "not a sibling",
+			nodes: []Test{dependencyTest(1, "a"), dependencyTest(2, "b", 1, 9)},
+			err:   ErrUnknownDependency,
+			msg:   "unknown dependency: test 'b' depends on test #9, which is not its sibling",
+		},
+		{
+			name:  "itself",
+			nodes: []Test{dependencyTest(1, "a", 1)},
+			err:   ErrDependencyCycle,
+			msg:   "dependency cycle between tests: 'a' needs 'a'",
+		},
+		{
+			name:  "cycle",
+			nodes: []Test{dependencyTest(1, "a", 3), dependencyTest(2, "b", 1), dependencyTest(3, "c", 2)},
+			err:   ErrDependencyCycle,
+			msg:   "dependency cycle between tests: 'a' needs 'c' needs 'b' needs 'a'",
+		},
+		{
+			name:  "cycle below",
+			nodes: []Test{dependencyTest(1, "a", 2), dependencyTest(2, "b", 3), dependencyTest(3, "c", 4), dependencyTest(4, "d", 3)},
+			err:   ErrDependencyCycle,
+			msg:   "dependency cycle between tests: 'c' needs 'd' needs 'c'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDependencies("test", tt.nodes)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) || err.Error() != tt.msg {
+				t.Fatalf("error %q, want %q", err, tt.msg)
+			}
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	// b fails, so c and, through c, e are skipped for it; d only needs a.
+	nodes := []Test{
+		dependencyTest(5, "e", 3, 4),
+		dependencyTest(1, "a"),
+		dependencyTest(2, "b", 1),
+		dependencyTest(3, "c", 2),
+		dependencyTest(4, "d", 1),
+	}
+	var ran, skipped []string
+	schedule(nodes, func(node Test) bool {
+		ran = append(ran, node.Name)
+		return node.Name != "b"
+	}, func(node, blocker Test) {
+		skipped = append(skipped, node.Name+" for "+blocker.Name)
+	})
+
+	if got := strings.Join(ran, " "); got != "a b d" {
+		t.Errorf("ran %s, want a b d", got)
+	}
+	if got := strings.Join(skipped, ", "); got != "c for b, e for b" {
+		t.Errorf("skipped %s, want c for b, e for b", got)
+	}
+}
+
+func TestScheduleParallel(t *testing.T) {
+	// A diamond, a to b and c to d, next to f, which fails and blocks g.
+	nodes := []Test{
+		dependencyTest(1, "a"),
+		dependencyTest(2, "b", 1),
+		dependencyTest(3, "c", 1),
+		dependencyTest(4, "d", 3, 2),
+		dependencyTest(5, "f"),
+		dependencyTest(6, "g", 5),
+	}
+
+	// b and c only return once both started, so they must run at the same time.
+	bothStarted := make(chan struct{})
+	var startedMu sync.Mutex
+	started := 0
+	var inputs []string
+	var skipped []string
+	outputs := scheduleParallel(nodes, func(node Test, in []string) (string, bool) {
+		switch node.Name {
+		case "b", "c":
+			startedMu.Lock()
+			if started++; started == 2 {
+				close(bothStarted)
+			}
+			startedMu.Unlock()
+			select {
+			case <-bothStarted:
+			case <-time.After(5 * time.Second):
+				t.Errorf("%s waited for its sibling in vain", node.Name)
+			}
+		case "d":
+			inputs = in
+		}
+		return strings.Join(append(in, node.Name), "+"), node.Name != "f"
+	}, func(node, blocker Test) {
+		startedMu.Lock()
+		defer startedMu.Unlock()
+		skipped = append(skipped, node.Name+" for "+blocker.Name)
+	})
+
+	// d gets its prerequisites' outputs in the order it names them.
+	if got := strings.Join(inputs, ", "); got != "a+c, a+b" {
+		t.Errorf("d got %s, want a+c, a+b", got)
+	}
+	if got := outputs[4]; got != "a+c+a+b+d" {
+		t.Errorf("d returned %s", got)
+	}
+	if got := outputs[5]; got != "f" {
+		t.Errorf("f returned %s, want its output although it failed", got)
+	}
+	if _, ok := outputs[6]; ok {
+		t.Error("g has an output although it was skipped")
+	}
+	if got := strings.Join(skipped, ", "); got != "g for f" {
+		t.Errorf("skipped %s, want g for f", got)
+	}
+}
+
+// newBarrierService echoes the last part of /echo/ and /barrier/ paths as {"value": ...}.
+// Requests to /barrier/ are held until n of them are in flight, so that a test only
+// passes when that many run at the same time.
+func newBarrierService(t *testing.T, n int) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	arrived := 0
+	all := make(chan struct{})
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": r.PathValue("value")})
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /echo/{value}", echo)
+	mux.HandleFunc("GET /barrier/{value}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if arrived++; arrived == n {
+			close(all)
+		}
+		mu.Unlock()
+		select {
+		case <-all:
+			echo(w, r)
+		case <-time.After(5 * time.Second):
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGradeSuiteConcurrentScenarios(t *testing.T) {
+	// Both scenarios capture v before either uses it, which only works if they run at
+	// the same time and don't share their variables.
+	srv := newBarrierService(t, 2)
+	gd := NewGrader(srv.URL, 0)
+	gd.Concurrency = 2
+	result := gradeTestSuite(t, context.Background(), gd, `
+name: Echo
+sections:
+  - name: Echo
+    scenarios:
+      - name: X
+        tests:
+          - name: capture
+            request: {method: GET, url: /barrier/x}
+            response: {status_code: 200, body: {"value": "$<v>"}}
+          - name: use
+            depends_on: [capture]
+            request: {method: GET, url: "/echo/{{v}}"}
+            response: {status_code: 200, body: {"value": "x"}}
+      - name: Y
+        tests:
+          - name: capture
+            request: {method: GET, url: /barrier/y}
+            response: {status_code: 200, body: {"value": "$<v>"}}
+          - name: use
+            depends_on: [capture]
+            request: {method: GET, url: "/echo/{{v}}"}
+            response: {status_code: 200, body: {"value": "y"}}
+`)
+	for _, scn := range findSection(t, result, "Echo").Scenarios {
+		for _, test := range scn.Tests {
+			if test.Status != StatusPassed {
+				t.Errorf("%s/%s: %s %q", scn.ScenarioName, test.TestName, test.Status, test.Message)
+			}
+		}
+	}
+	checkScore(t, "project", result.Status, result.Score, result.MaxScore, StatusPassed, 1, 1)
+}
+
+func TestGradeSuiteDiamondVariables(t *testing.T) {
+	// B and C both start with A's variables and run at the same time; D gets all three.
+	srv := newBarrierService(t, 2)
+	gd := NewGrader(srv.URL, 0)
+	gd.Concurrency = 2
+	result := gradeTestSuite(t, context.Background(), gd, `
+name: Echo
+sections:
+  - name: A
+    scenarios:
+      - name: A
+        tests:
+          - name: a
+            request: {method: GET, url: /echo/a}
+            response: {status_code: 200, body: {"value": "$<a>"}}
+  - name: B
+    depends_on: [A]
+    scenarios:
+      - name: B
+        tests:
+          - name: b
+            request: {method: GET, url: "/barrier/{{a}}b"}
+            response: {status_code: 200, body: {"value": "$<b>"}}
+  - name: C
+    depends_on: [A]
+    scenarios:
+      - name: C
+        tests:
+          - name: c
+            request: {method: GET, url: "/barrier/{{a}}c"}
+            response: {status_code: 200, body: {"value": "$<c>"}}
+  - name: D
+    depends_on: [B, C]
+    scenarios:
+      - name: D
+        tests:
+          - name: d
+            request: {method: GET, url: "/echo/{{a}}-{{b}}-{{c}}"}
+            response: {status_code: 200, body: {"value": "a-ab-ac"}}
+`)
+	for _, sec := range result.Sections {
+		if sec.Status != StatusPassed {
+			t.Errorf("section %s: %s %q", sec.SectionName, sec.Status, sec.Message)
+			for _, scn := range sec.Scenarios {
+				for _, test := range scn.Tests {
+					t.Logf("test %s: %s %q", test.TestName, test.Status, test.Message)
+				}
+			}
+		}
+	}
+}
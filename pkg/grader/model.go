@@ -10,39 +10,61 @@ type Project struct {
 	m.Model
 	Name     string    `json:"name"`
 	Due      time.Time `json:"due"`
+	CourseID uint      `json:"course_id"`
 	Sections []Section `json:"sections"`
 }
 
 type Section struct {
 	m.Model
-	Name        string   `json:"name"`
-	DependsOn   *Section `json:"depends_on"`
-	DependsOnID *uint    `json:"depends_on_id"`
-	ProjectID   uint     `json:"project_id"`
+	Name         string     `json:"name"`
+	Points       float64    `json:"points"`
+	DependsOnIDs []uint     `json:"depends_on_ids" gorm:"-"` // Loaded from section_dependencies
+	ProjectID    uint       `json:"project_id"`
+	Scenarios    []Scenario `json:"scenarios,omitempty"`
 }
 
 type Scenario struct {
 	m.Model
-	Name        string    `json:"name"`
-	DependsOn   *Scenario `json:"depends_on"`
-	DependsOnID *uint     `json:"depends_on_id"`
-	Section     Section   `json:"section"`
-	SectionID   uint      `json:"section_id"`
+	Name         string  `json:"name"`
+	Points       float64 `json:"points"`
+	DependsOnIDs []uint  `json:"depends_on_ids" gorm:"-"` // Loaded from scenario_dependencies
+	Section      Section `json:"section"`
+	SectionID    uint    `json:"section_id"`
+	Tests        []Test  `json:"tests,omitempty"`
 }
 
 type Test struct {
 	m.Model
 	Name     string    `json:"name"`
+	Points   float64   `json:"points"`
 	Request  TRequest  `json:"request" gorm:"embedded"`
 	Response TResponse `json:"response" gorm:"embedded"`
 
-	DependsOn   *Test `json:"depends_on"`
-	DependsOnID *uint `json:"depends_on_id"`
+	DependsOnIDs []uint `json:"depends_on_ids" gorm:"-"` // Loaded from test_dependencies
 
 	Scenario   Scenario `json:"scenario"`
 	ScenarioID uint     `json:"scenario_id"`
 }
 
+// SectionDependency makes a section run only after another section of the same project passed.
+// A section may have any number of them.
+type SectionDependency struct {
+	SectionID   uint `json:"section_id" gorm:"primaryKey"`
+	DependsOnID uint `json:"depends_on_id" gorm:"primaryKey"`
+}
+
+// ScenarioDependency makes a scenario run only after another scenario of the same section passed.
+type ScenarioDependency struct {
+	ScenarioID  uint `json:"scenario_id" gorm:"primaryKey"`
+	DependsOnID uint `json:"depends_on_id" gorm:"primaryKey"`
+}
+
+// TestDependency makes a test run only after another test of the same scenario passed.
+type TestDependency struct {
+	TestID      uint `json:"test_id" gorm:"primaryKey"`
+	DependsOnID uint `json:"depends_on_id" gorm:"primaryKey"`
+}
+
 type TRequest struct {
 	Url     string    `json:"url"`
 	Method  string    `json:"method"`
@@ -52,8 +74,8 @@ type TRequest struct {
 
 type THeader struct {
 	m.Model
-	Key    string `json:"key" gorm:"hkey"`
-	Value  string `json:"value" gorm:"hvalue"`
+	Key    string `json:"key" gorm:"column:hkey"`
+	Value  string `json:"value" gorm:"column:hvalue"`
 	Test   Test   `json:"test"`
 	TestID uint   `json:"test_id"`
 }
@@ -61,26 +83,83 @@ type THeader struct {
 type TResponse struct {
 	StatusCode uint   `json:"status_code"`
 	ResBody    string `json:"body"`
-	// Headers    []THeader `json:"headers"` // TODO
+	// ObjectMode and ArrayMode are how ResBody is compared with the actual body, and
+	// Comparisons change them below given paths; see compare.go. Empty means the default.
+	ObjectMode  ObjectMode    `json:"object_mode"`
+	ArrayMode   ArrayMode     `json:"array_mode"`
+	Comparisons []TComparison `json:"comparisons" gorm:"-"`
+	// Headers are loaded explicitly: GORM can't keep two relations named
+	// Headers on the same (embedded) schema.
+	Headers []TResHeader `json:"headers" gorm:"-"`
+	// Assertions check single values of the body; see assertion.go.
+	Assertions []TAssertion `json:"assertions" gorm:"-"`
+}
+
+// TResHeader is a response header a test expects. Its value may capture
+// variables with $<var_name>, e.g. "/users/$<user_id>" for a Location header.
+// A Content-Type also matches by its media type, ignoring parameters like charset.
+type TResHeader struct {
+	m.Model
+	Key    string `json:"key" gorm:"column:hkey"`
+	Value  string `json:"value" gorm:"column:hvalue"`
+	TestID uint   `json:"test_id"`
+}
+
+// TAssertion is an assertion a test makes about its response body, e.g. "$.items.length > 0".
+type TAssertion struct {
+	m.Model
+	Expr   string `json:"expr"`
+	TestID uint   `json:"test_id"`
+}
+
+// TComparison sets how the part of a test's expected body at Path, e.g. "$.items" or
+// "$.users[*]", is compared. Array elements are named with [*], never by index. An
+// empty mode keeps the one from above.
+type TComparison struct {
+	m.Model
+	Path       string     `json:"path"`
+	ObjectMode ObjectMode `json:"object_mode"`
+	ArrayMode  ArrayMode  `json:"array_mode"`
+	TestID     uint       `json:"test_id"`
 }
 
 // GradingStatus defines the status of a grading task.
 type GradingStatus string
 
 const (
-	StatusProcessing GradingStatus = "processing"
-	StatusPassed     GradingStatus = "passed"
-	StatusFailed     GradingStatus = "failed"
+	StatusQueued      GradingStatus = "queued"
+	StatusProcessing  GradingStatus = "processing"
+	StatusPassed      GradingStatus = "passed"
+	StatusFailed      GradingStatus = "failed"
+	StatusInterrupted GradingStatus = "interrupted"
+	StatusSkipped     GradingStatus = "skipped"
+	StatusCancelled   GradingStatus = "cancelled"
 )
 
-// Grader Result Structs for Database Storage
+// FailureReason tells why a test failed, so that a service answering wrong can be
+// told apart from one that couldn't be reached at all.
+type FailureReason string
+
+const (
+	ReasonMismatch      FailureReason = "mismatch"
+	ReasonTimeout       FailureReason = "timeout"
+	ReasonRequestFailed FailureReason = "request_failed"
+	ReasonTargetBlocked FailureReason = "target_blocked"
+)
+
+// Grader Result Structs for Database Storage.
+// Score is the number of points earned out of MaxScore; see the rollup in grader.go.
 type ProjectResult struct {
 	m.Model
 	ProjectID   uint            `json:"project_id"`
 	ProjectName string          `json:"project_name"`
-	UserID      uint            `json:"user_id"` // Link to the user who initiated the grading
+	UserID      uint            `json:"user_id"`           // Link to the user who initiated the grading
+	TeamID      *uint           `json:"team_id,omitempty"` // The team the grading counts for, if the user is in one
 	Status      GradingStatus   `json:"status"`
 	Message     string          `json:"message,omitempty"`
+	Output      string          `json:"output,omitempty"` // What an uploaded submission printed while it was graded
+	Score       float64         `json:"score"`
+	MaxScore    float64         `json:"max_score"`
 	Sections    []SectionResult `json:"sections" gorm:"foreignKey:ProjectResultID"`
 }
 
@@ -93,7 +172,8 @@ type SectionResult struct {
 	Message         string           `json:"message,omitempty"`
 	TotalScenarios  uint             `json:"total_scenarios"`
 	PassedScenarios uint             `json:"passed_scenarios"`
-	Score           float64          `json:"score"` // Percentage of passed scenarios
+	Score           float64          `json:"score"`
+	MaxScore        float64          `json:"max_score"`
 	Scenarios       []ScenarioResult `json:"scenarios" gorm:"foreignKey:SectionResultID"`
 }
 
@@ -104,6 +184,8 @@ type ScenarioResult struct {
 	SectionResultID uint          `json:"section_result_id"` // Foreign key to SectionResult
 	Status          GradingStatus `json:"status"`
 	Message         string        `json:"message,omitempty"`
+	Score           float64       `json:"score"`
+	MaxScore        float64       `json:"max_score"`
 	Tests           []TestResult  `json:"tests" gorm:"foreignKey:ScenarioResultID"`
 }
 
@@ -113,12 +195,28 @@ type TestResult struct {
 	TestName         string        `json:"test_name"`
 	ScenarioResultID uint          `json:"scenario_result_id"` // Foreign key to ScenarioResult
 	Status           GradingStatus `json:"status"`
+	Reason           FailureReason `json:"reason,omitempty"`
 	Message          string        `json:"message,omitempty"`
+	Score            float64       `json:"score"`
+	MaxScore         float64       `json:"max_score"`
 	// Store request/response details if needed for auditing/debugging
 	ActualStatusCode     uint   `json:"actual_status_code,omitempty"`
 	ExpectedStatusCode   uint   `json:"expected_status_code,omitempty"`
 	ActualResponseBody   string `json:"actual_response_body,omitempty"`
 	ExpectedResponseBody string `json:"expected_response_body,omitempty"`
+
+	Assertions []AssertionResult `json:"assertions,omitempty" gorm:"foreignKey:TestResultID"`
+}
+
+// AssertionResult tells how one of a test's assertions fared. Actual is the JSON found
+// at the assertion's path, empty if there was nothing.
+type AssertionResult struct {
+	m.Model
+	TestResultID uint   `json:"test_result_id"`
+	Expr         string `json:"expr"`
+	Passed       bool   `json:"passed"`
+	Actual       string `json:"actual,omitempty"`
+	Message      string `json:"message,omitempty"`
 }
 
 func (Project) TableName() string {
@@ -137,10 +235,34 @@ func (Test) TableName() string {
 	return "tests"
 }
 
+func (SectionDependency) TableName() string {
+	return "section_dependencies"
+}
+
+func (ScenarioDependency) TableName() string {
+	return "scenario_dependencies"
+}
+
+func (TestDependency) TableName() string {
+	return "test_dependencies"
+}
+
 func (THeader) TableName() string {
 	return "theaders"
 }
 
+func (TResHeader) TableName() string {
+	return "tresheaders"
+}
+
+func (TAssertion) TableName() string {
+	return "tassertions"
+}
+
+func (TComparison) TableName() string {
+	return "tcomparisons"
+}
+
 func (ProjectResult) TableName() string {
 	return "project_results"
 }
@@ -156,3 +278,7 @@ func (ScenarioResult) TableName() string {
 func (TestResult) TableName() string {
 	return "test_results"
 }
+
+func (AssertionResult) TableName() string {
+	return "assertion_results"
+}
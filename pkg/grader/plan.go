@@ -0,0 +1,277 @@
+package grader
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// executionPlan is the complete Section→Scenario→Test tree of a project,
+// loaded up front so that grading never goes back to the database for it.
+type executionPlan struct {
+	Project  Project
+	Sections []*sectionPlan
+}
+
+type sectionPlan struct {
+	Section   Section
+	Scenarios []*scenarioPlan
+}
+
+type scenarioPlan struct {
+	Scenario Scenario
+	Tests    []Test
+}
+
+// maxScore is the most a project can score: the sum of its sections' points.
+func (p *executionPlan) maxScore() float64 {
+	var total float64
+	for _, sec := range p.Sections {
+		total += sec.Section.Points
+	}
+	return total
+}
+
+// maxScore is the sum of the points of the section's scenarios.
+func (sp *sectionPlan) maxScore() float64 {
+	var total float64
+	for _, scn := range sp.Scenarios {
+		total += scn.Scenario.Points
+	}
+	return total
+}
+
+// maxScore is the sum of the points of the scenario's tests.
+func (sp *scenarioPlan) maxScore() float64 {
+	var total float64
+	for _, test := range sp.Tests {
+		total += test.Points
+	}
+	return total
+}
+
+// planRows are the rows of a project's tree, as loaded from storage.
+type planRows struct {
+	Sections        []Section
+	Scenarios       []Scenario
+	Tests           []Test
+	RequestHeaders  []THeader
+	ResponseHeaders []TResHeader
+	Assertions      []TAssertion
+	Comparisons     []TComparison
+	SectionDeps     []SectionDependency
+	ScenarioDeps    []ScenarioDependency
+	TestDeps        []TestDependency
+}
+
+// loadPlan loads a project and its whole tree in a fixed number of queries,
+// regardless of how many sections, scenarios and tests it has. The plan's
+// dependencies are validated before it is returned.
+func loadPlan(db *gorm.DB, projID uint) (*executionPlan, error) {
+	proj, rows, err := loadRows(db, projID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := buildPlan(proj, rows)
+	if err := plan.validate(); err != nil {
+		return nil, fmt.Errorf("invalid project %d: %w", proj.ID, err)
+	}
+	return plan, nil
+}
+
+// LoadProject loads a project with its whole tree: sections, scenarios, tests,
+// their headers and dependencies.
+func LoadProject(db *gorm.DB, projID uint) (*Project, error) {
+	proj, rows, err := loadRows(db, projID)
+	if err != nil {
+		return nil, err
+	}
+	return buildPlan(proj, rows).tree(), nil
+}
+
+// ValidateProject checks that a project's dependencies can be scheduled for grading.
+func ValidateProject(db *gorm.DB, projID uint) error {
+	_, err := loadPlan(db, projID)
+	return err
+}
+
+// loadRows loads a project and the rows of its tree.
+func loadRows(db *gorm.DB, projID uint) (Project, planRows, error) {
+	var proj Project
+	var rows planRows
+	if err := db.First(&proj, projID).Error; err != nil {
+		return proj, rows, fmt.Errorf("project not found: %w", err)
+	}
+
+	if err := db.Where("project_id = ?", proj.ID).Order("id").Find(&rows.Sections).Error; err != nil {
+		return proj, rows, fmt.Errorf("failed to load sections for project %d: %w", proj.ID, err)
+	}
+	secIDs := ids(rows.Sections, func(s Section) uint { return s.ID })
+	if err := findByParent(db, "section_id", secIDs, &rows.Scenarios); err != nil {
+		return proj, rows, fmt.Errorf("failed to load scenarios for project %d: %w", proj.ID, err)
+	}
+	scnIDs := ids(rows.Scenarios, func(s Scenario) uint { return s.ID })
+	if err := findByParent(db, "scenario_id", scnIDs, &rows.Tests); err != nil {
+		return proj, rows, fmt.Errorf("failed to load tests for project %d: %w", proj.ID, err)
+	}
+
+	testIDs := ids(rows.Tests, func(t Test) uint { return t.ID })
+	if err := findByParent(db, "test_id", testIDs, &rows.RequestHeaders); err != nil {
+		return proj, rows, fmt.Errorf("failed to load request headers for project %d: %w", proj.ID, err)
+	}
+	if err := findByParent(db, "test_id", testIDs, &rows.ResponseHeaders); err != nil {
+		return proj, rows, fmt.Errorf("failed to load response headers for project %d: %w", proj.ID, err)
+	}
+	if err := findByParent(db, "test_id", testIDs, &rows.Assertions); err != nil {
+		return proj, rows, fmt.Errorf("failed to load assertions for project %d: %w", proj.ID, err)
+	}
+	if err := findByParent(db, "test_id", testIDs, &rows.Comparisons); err != nil {
+		return proj, rows, fmt.Errorf("failed to load comparisons for project %d: %w", proj.ID, err)
+	}
+
+	if err := findDependencies(db, "section_id", secIDs, &rows.SectionDeps); err != nil {
+		return proj, rows, fmt.Errorf("failed to load section dependencies for project %d: %w", proj.ID, err)
+	}
+	if err := findDependencies(db, "scenario_id", scnIDs, &rows.ScenarioDeps); err != nil {
+		return proj, rows, fmt.Errorf("failed to load scenario dependencies for project %d: %w", proj.ID, err)
+	}
+	if err := findDependencies(db, "test_id", testIDs, &rows.TestDeps); err != nil {
+		return proj, rows, fmt.Errorf("failed to load test dependencies for project %d: %w", proj.ID, err)
+	}
+	return proj, rows, nil
+}
+
+// buildPlan assembles the loaded rows into a tree, keeping the load order within each level.
+func buildPlan(proj Project, rows planRows) *executionPlan {
+	sectionDeps := make(map[uint][]uint)
+	for _, d := range rows.SectionDeps {
+		sectionDeps[d.SectionID] = append(sectionDeps[d.SectionID], d.DependsOnID)
+	}
+	scenarioDeps := make(map[uint][]uint)
+	for _, d := range rows.ScenarioDeps {
+		scenarioDeps[d.ScenarioID] = append(scenarioDeps[d.ScenarioID], d.DependsOnID)
+	}
+	testDeps := make(map[uint][]uint)
+	for _, d := range rows.TestDeps {
+		testDeps[d.TestID] = append(testDeps[d.TestID], d.DependsOnID)
+	}
+
+	reqHeadersByTest := make(map[uint][]THeader)
+	for _, h := range rows.RequestHeaders {
+		reqHeadersByTest[h.TestID] = append(reqHeadersByTest[h.TestID], h)
+	}
+	resHeadersByTest := make(map[uint][]TResHeader)
+	for _, h := range rows.ResponseHeaders {
+		resHeadersByTest[h.TestID] = append(resHeadersByTest[h.TestID], h)
+	}
+
+	assertionsByTest := make(map[uint][]TAssertion)
+	for _, a := range rows.Assertions {
+		assertionsByTest[a.TestID] = append(assertionsByTest[a.TestID], a)
+	}
+
+	comparisonsByTest := make(map[uint][]TComparison)
+	for _, c := range rows.Comparisons {
+		comparisonsByTest[c.TestID] = append(comparisonsByTest[c.TestID], c)
+	}
+
+	testsByScenario := make(map[uint][]Test)
+	for _, test := range rows.Tests {
+		test.Request.Headers = reqHeadersByTest[test.ID]
+		test.Response.Headers = resHeadersByTest[test.ID]
+		test.Response.Assertions = assertionsByTest[test.ID]
+		test.Response.Comparisons = comparisonsByTest[test.ID]
+		test.DependsOnIDs = testDeps[test.ID]
+		testsByScenario[test.ScenarioID] = append(testsByScenario[test.ScenarioID], test)
+	}
+
+	scenariosBySection := make(map[uint][]*scenarioPlan)
+	for _, scn := range rows.Scenarios {
+		scn.DependsOnIDs = scenarioDeps[scn.ID]
+		scenariosBySection[scn.SectionID] = append(scenariosBySection[scn.SectionID], &scenarioPlan{
+			Scenario: scn,
+			Tests:    testsByScenario[scn.ID],
+		})
+	}
+
+	plan := &executionPlan{Project: proj}
+	for _, sec := range rows.Sections {
+		sec.DependsOnIDs = sectionDeps[sec.ID]
+		plan.Sections = append(plan.Sections, &sectionPlan{
+			Section:   sec,
+			Scenarios: scenariosBySection[sec.ID],
+		})
+	}
+	return plan
+}
+
+// tree turns the plan back into a project with nested sections, scenarios and tests.
+func (p *executionPlan) tree() *Project {
+	proj := p.Project
+	proj.Sections = make([]Section, 0, len(p.Sections))
+	for _, sp := range p.Sections {
+		sec := sp.Section
+		sec.Scenarios = make([]Scenario, 0, len(sp.Scenarios))
+		for _, scp := range sp.Scenarios {
+			scn := scp.Scenario
+			scn.Tests = scp.Tests
+			sec.Scenarios = append(sec.Scenarios, scn)
+		}
+		proj.Sections = append(proj.Sections, sec)
+	}
+	return &proj
+}
+
+// validate rejects plans whose dependencies can't be scheduled or whose assertions or
+// comparisons can't be parsed, before anything runs.
+func (p *executionPlan) validate() error {
+	if err := validateDependencies("section", p.Sections); err != nil {
+		return err
+	}
+	for _, sec := range p.Sections {
+		if err := validateDependencies("scenario", sec.Scenarios); err != nil {
+			return fmt.Errorf("section '%s': %w", sec.Section.Name, err)
+		}
+		for _, scn := range sec.Scenarios {
+			if err := validateDependencies("test", scn.Tests); err != nil {
+				return fmt.Errorf("section '%s', scenario '%s': %w", sec.Section.Name, scn.Scenario.Name, err)
+			}
+			for _, test := range scn.Tests {
+				if _, err := newComparison(test.Response); err != nil {
+					return fmt.Errorf("section '%s', scenario '%s', test '%s': %w", sec.Section.Name, scn.Scenario.Name, test.Name, err)
+				}
+				for _, a := range test.Response.Assertions {
+					if _, err := parseAssertion(a.Expr); err != nil {
+						return fmt.Errorf("section '%s', scenario '%s', test '%s': %w", sec.Section.Name, scn.Scenario.Name, test.Name, err)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// findByParent loads the rows whose parentColumn is one of parentIDs, ordered by id.
+func findByParent(db *gorm.DB, parentColumn string, parentIDs []uint, dest interface{}) error {
+	if len(parentIDs) == 0 {
+		return nil
+	}
+	return db.Where(parentColumn+" IN ?", parentIDs).Order("id").Find(dest).Error
+}
+
+// findDependencies loads the dependency rows of the nodes whose nodeColumn is one of nodeIDs.
+func findDependencies(db *gorm.DB, nodeColumn string, nodeIDs []uint, dest interface{}) error {
+	if len(nodeIDs) == 0 {
+		return nil
+	}
+	return db.Where(nodeColumn+" IN ?", nodeIDs).Order(nodeColumn + ", depends_on_id").Find(dest).Error
+}
+
+func ids[T any](nodes []T, id func(T) uint) []uint {
+	result := make([]uint, len(nodes))
+	for i, node := range nodes {
+		result[i] = id(node)
+	}
+	return result
+}
@@ -0,0 +1,24 @@
+package grader
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// RecoverInterrupted marks every result that is still processing as interrupted, recording reason
+// as its message. Nothing can still be working on such results when this runs on startup,
+// so it must not be called while gradings are in progress.
+func RecoverInterrupted(db *gorm.DB, reason string) (int64, error) {
+	var recovered int64
+	for _, model := range []interface{}{&TestResult{}, &ScenarioResult{}, &SectionResult{}, &ProjectResult{}} {
+		result := db.Model(model).
+			Where("status = ?", StatusProcessing).
+			Updates(map[string]interface{}{"status": StatusInterrupted, "message": reason})
+		if result.Error != nil {
+			return recovered, fmt.Errorf("failed to recover interrupted results: %w", result.Error)
+		}
+		recovered += result.RowsAffected
+	}
+	return recovered, nil
+}
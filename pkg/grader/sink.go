@@ -0,0 +1,206 @@
+package grader
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ResultSink is where a grading run writes its results as it goes. Results are created
+// before their children, which point at them by ID, and saved once they're final. The
+// grader never reads them back: statuses and scores roll up from what it recorded.
+type ResultSink interface {
+	CreateProjectResult(result *ProjectResult) error
+	SaveProjectResult(result *ProjectResult) error
+	CreateSectionResult(result *SectionResult) error
+	SaveSectionResult(result *SectionResult) error
+	CreateScenarioResult(result *ScenarioResult) error
+	SaveScenarioResult(result *ScenarioResult) error
+	CreateTestResult(result *TestResult) error
+	CreateTestResults(results []TestResult) error
+	SaveTestResult(result *TestResult) error
+}
+
+// gormSink writes results to the database.
+type gormSink struct {
+	db *gorm.DB
+}
+
+// NewGormSink returns a ResultSink that writes results to db.
+func NewGormSink(db *gorm.DB) ResultSink {
+	return &gormSink{db}
+}
+
+func (s *gormSink) CreateProjectResult(result *ProjectResult) error {
+	return s.db.Create(result).Error
+}
+
+func (s *gormSink) SaveProjectResult(result *ProjectResult) error {
+	return s.db.Save(result).Error
+}
+
+func (s *gormSink) CreateSectionResult(result *SectionResult) error {
+	return s.db.Create(result).Error
+}
+
+func (s *gormSink) SaveSectionResult(result *SectionResult) error {
+	return s.db.Save(result).Error
+}
+
+func (s *gormSink) CreateScenarioResult(result *ScenarioResult) error {
+	return s.db.Create(result).Error
+}
+
+func (s *gormSink) SaveScenarioResult(result *ScenarioResult) error {
+	return s.db.Save(result).Error
+}
+
+func (s *gormSink) CreateTestResult(result *TestResult) error {
+	return s.db.Create(result).Error
+}
+
+func (s *gormSink) CreateTestResults(results []TestResult) error {
+	return s.db.Create(&results).Error
+}
+
+func (s *gormSink) SaveTestResult(result *TestResult) error {
+	return s.db.Save(result).Error
+}
+
+// MemorySink keeps results in memory, for gradings that don't need to outlive the
+// process, e.g. checking a suite from the command line or trying the grader out.
+type MemorySink struct {
+	mu        sync.Mutex
+	lastID    uint
+	projects  []ProjectResult
+	sections  []SectionResult
+	scenarios []ScenarioResult
+	tests     []TestResult
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) nextID() uint {
+	s.lastID++
+	return s.lastID
+}
+
+func (s *MemorySink) CreateProjectResult(result *ProjectResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result.ID = s.nextID()
+	s.projects = append(s.projects, *result)
+	return nil
+}
+
+func (s *MemorySink) SaveProjectResult(result *ProjectResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return replace(s.projects, result, func(r ProjectResult) uint { return r.ID })
+}
+
+func (s *MemorySink) CreateSectionResult(result *SectionResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result.ID = s.nextID()
+	s.sections = append(s.sections, *result)
+	return nil
+}
+
+func (s *MemorySink) SaveSectionResult(result *SectionResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return replace(s.sections, result, func(r SectionResult) uint { return r.ID })
+}
+
+func (s *MemorySink) CreateScenarioResult(result *ScenarioResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result.ID = s.nextID()
+	s.scenarios = append(s.scenarios, *result)
+	return nil
+}
+
+func (s *MemorySink) SaveScenarioResult(result *ScenarioResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return replace(s.scenarios, result, func(r ScenarioResult) uint { return r.ID })
+}
+
+func (s *MemorySink) CreateTestResult(result *TestResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result.ID = s.nextID()
+	s.tests = append(s.tests, *result)
+	return nil
+}
+
+func (s *MemorySink) CreateTestResults(results []TestResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range results {
+		results[i].ID = s.nextID()
+		s.tests = append(s.tests, results[i])
+	}
+	return nil
+}
+
+func (s *MemorySink) SaveTestResult(result *TestResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return replace(s.tests, result, func(r TestResult) uint { return r.ID })
+}
+
+// ProjectResult returns a project result with its whole tree, in the order the
+// results were created.
+func (s *MemorySink) ProjectResult(id uint) (*ProjectResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var project *ProjectResult
+	for i := range s.projects {
+		if s.projects[i].ID == id {
+			result := s.projects[i]
+			project = &result
+		}
+	}
+	if project == nil {
+		return nil, fmt.Errorf("project result %d not found", id)
+	}
+
+	project.Sections = nil
+	for _, sec := range s.sections {
+		if sec.ProjectResultID != id {
+			continue
+		}
+		sec.Scenarios = nil
+		for _, scn := range s.scenarios {
+			if scn.SectionResultID != sec.ID {
+				continue
+			}
+			scn.Tests = nil
+			for _, test := range s.tests {
+				if test.ScenarioResultID == scn.ID {
+					scn.Tests = append(scn.Tests, test)
+				}
+			}
+			sec.Scenarios = append(sec.Scenarios, scn)
+		}
+		project.Sections = append(project.Sections, sec)
+	}
+	return project, nil
+}
+
+// replace overwrites the result in results that has the ID of result.
+func replace[T any](results []T, result *T, id func(T) uint) error {
+	for i := range results {
+		if id(results[i]) == id(*result) {
+			results[i] = *result
+			return nil
+		}
+	}
+	return fmt.Errorf("result %d not found", id(*result))
+}
@@ -0,0 +1,457 @@
+package grader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Suite is a project written down as a file, to be kept in version control and
+// imported into the grader. Sections, scenarios and tests are identified by their
+// names, which must be unique among siblings, and depend on each other by name:
+//
+//	name: Blog API
+//	due: 2025-09-01T23:59:00Z
+//	sections:
+//	  - name: Auth
+//	    scenarios:
+//	      - name: Sign up
+//	        tests:
+//	          - name: Create user
+//	            request:
+//	              method: POST
+//	              url: /users
+//	              body: {"username": "bob"}
+//	            response:
+//	              status_code: 201
+//	              body: {"id": "$<user_id>"}
+//	              object_mode: strict
+//	              assertions:
+//	                - $.username == bob
+//	  - name: Posts
+//	    depends_on: [Auth]
+//
+// Points default to 1. A body may be written as a string or, for JSON bodies, as
+// structured YAML; exported suites always write it as the string that is stored.
+type Suite struct {
+	Name     string         `json:"name" yaml:"name"`
+	Due      time.Time      `json:"due" yaml:"due"`
+	Sections []SuiteSection `json:"sections" yaml:"sections"`
+}
+
+type SuiteSection struct {
+	Name      string          `json:"name" yaml:"name"`
+	Points    *float64        `json:"points,omitempty" yaml:"points,omitempty"`
+	DependsOn []string        `json:"depends_on,omitempty" yaml:"depends_on,omitempty,flow"`
+	Scenarios []SuiteScenario `json:"scenarios,omitempty" yaml:"scenarios,omitempty"`
+}
+
+type SuiteScenario struct {
+	Name      string      `json:"name" yaml:"name"`
+	Points    *float64    `json:"points,omitempty" yaml:"points,omitempty"`
+	DependsOn []string    `json:"depends_on,omitempty" yaml:"depends_on,omitempty,flow"`
+	Tests     []SuiteTest `json:"tests,omitempty" yaml:"tests,omitempty"`
+}
+
+type SuiteTest struct {
+	Name      string        `json:"name" yaml:"name"`
+	Points    *float64      `json:"points,omitempty" yaml:"points,omitempty"`
+	DependsOn []string      `json:"depends_on,omitempty" yaml:"depends_on,omitempty,flow"`
+	Request   SuiteRequest  `json:"request" yaml:"request"`
+	Response  SuiteResponse `json:"response" yaml:"response"`
+}
+
+type SuiteRequest struct {
+	Method  string        `json:"method" yaml:"method"`
+	Url     string        `json:"url" yaml:"url"`
+	Headers []SuiteHeader `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body    SuiteBody     `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+type SuiteResponse struct {
+	StatusCode uint          `json:"status_code" yaml:"status_code"`
+	Headers    []SuiteHeader `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Body       SuiteBody     `json:"body,omitempty" yaml:"body,omitempty"`
+	Assertions []string      `json:"assertions,omitempty" yaml:"assertions,omitempty"`
+	// ObjectMode, ArrayMode and Comparisons are how Body is compared; see compare.go.
+	ObjectMode  ObjectMode        `json:"object_mode,omitempty" yaml:"object_mode,omitempty"`
+	ArrayMode   ArrayMode         `json:"array_mode,omitempty" yaml:"array_mode,omitempty"`
+	Comparisons []SuiteComparison `json:"comparisons,omitempty" yaml:"comparisons,omitempty"`
+}
+
+// SuiteComparison changes how the part of a body at Path is compared.
+type SuiteComparison struct {
+	Path       string     `json:"path" yaml:"path"`
+	ObjectMode ObjectMode `json:"object_mode,omitempty" yaml:"object_mode,omitempty"`
+	ArrayMode  ArrayMode  `json:"array_mode,omitempty" yaml:"array_mode,omitempty"`
+}
+
+// Model returns the response as stored, without its headers and assertions, which
+// are rows of their own.
+func (r SuiteResponse) Model() TResponse {
+	resp := TResponse{
+		StatusCode: r.StatusCode,
+		ResBody:    string(r.Body),
+		ObjectMode: r.ObjectMode,
+		ArrayMode:  r.ArrayMode,
+	}
+	for _, c := range r.Comparisons {
+		resp.Comparisons = append(resp.Comparisons, TComparison{Path: c.Path, ObjectMode: c.ObjectMode, ArrayMode: c.ArrayMode})
+	}
+	return resp
+}
+
+// SuiteHeader is a list entry rather than a map key, so that headers keep their order
+// and may repeat.
+type SuiteHeader struct {
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// SuiteBody is a request or response body. Structured values are stored as JSON.
+type SuiteBody string
+
+func (b *SuiteBody) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = SuiteBody(s)
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	compact, err := json.Marshal(v)
+	*b = SuiteBody(compact)
+	return err
+}
+
+func (b *SuiteBody) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		*b = SuiteBody(node.Value)
+		return nil
+	}
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return err
+	}
+	compact, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("line %d: body can't be written as JSON: %w", node.Line, err)
+	}
+	*b = SuiteBody(compact)
+	return nil
+}
+
+// SuiteFormat is the encoding of a suite file.
+type SuiteFormat string
+
+const (
+	SuiteYAML SuiteFormat = "yaml"
+	SuiteJSON SuiteFormat = "json"
+)
+
+// ReadSuite decodes and validates a suite. YAML being a superset of JSON, it reads
+// either; unknown fields are refused so that typos don't go unnoticed.
+func ReadSuite(r io.Reader) (*Suite, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var suite Suite
+	if err := dec.Decode(&suite); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: the file is empty", ErrInvalidSuite)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSuite, err.Error())
+	}
+	if err := suite.Validate(); err != nil {
+		return nil, err
+	}
+	return &suite, nil
+}
+
+// WriteSuite encodes a suite in the given format.
+func WriteSuite(w io.Writer, suite *Suite, format SuiteFormat) error {
+	switch format {
+	case SuiteJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(suite)
+	case SuiteYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(suite); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return fmt.Errorf("unknown suite format %q", format)
+}
+
+// Validate checks that names are given and unique among siblings, that dependencies
+// name siblings, that points aren't negative and that tests have a method, a status
+// code and assertions and comparisons that parse.
+// Dependency cycles are caught when the suite is imported.
+func (s *Suite) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("%w: the project has no name", ErrInvalidSuite)
+	}
+	secNames := make([]string, len(s.Sections))
+	for i, sec := range s.Sections {
+		secNames[i] = sec.Name
+	}
+	if err := checkSuiteNames("section", s.Name, secNames); err != nil {
+		return err
+	}
+
+	for _, sec := range s.Sections {
+		if err := checkSuiteDependencies("section", sec.Name, sec.DependsOn, secNames); err != nil {
+			return err
+		}
+		if err := checkSuitePoints("section", sec.Name, sec.Points); err != nil {
+			return err
+		}
+		scnNames := make([]string, len(sec.Scenarios))
+		for i, scn := range sec.Scenarios {
+			scnNames[i] = scn.Name
+		}
+		if err := checkSuiteNames("scenario", sec.Name, scnNames); err != nil {
+			return err
+		}
+
+		for _, scn := range sec.Scenarios {
+			if err := checkSuiteDependencies("scenario", scn.Name, scn.DependsOn, scnNames); err != nil {
+				return err
+			}
+			if err := checkSuitePoints("scenario", scn.Name, scn.Points); err != nil {
+				return err
+			}
+			testNames := make([]string, len(scn.Tests))
+			for i, test := range scn.Tests {
+				testNames[i] = test.Name
+			}
+			if err := checkSuiteNames("test", scn.Name, testNames); err != nil {
+				return err
+			}
+
+			for _, test := range scn.Tests {
+				if err := checkSuiteDependencies("test", test.Name, test.DependsOn, testNames); err != nil {
+					return err
+				}
+				if err := checkSuitePoints("test", test.Name, test.Points); err != nil {
+					return err
+				}
+				if !slices.Contains(suiteMethods, test.Request.Method) {
+					return fmt.Errorf("%w: test '%s' has method %q, expected one of %v", ErrInvalidSuite, test.Name, test.Request.Method, suiteMethods)
+				}
+				if test.Response.StatusCode < 100 || test.Response.StatusCode > 599 {
+					return fmt.Errorf("%w: test '%s' expects status code %d", ErrInvalidSuite, test.Name, test.Response.StatusCode)
+				}
+				if _, err := newComparison(test.Response.Model()); err != nil {
+					return fmt.Errorf("%w: test '%s': %s", ErrInvalidSuite, test.Name, err.Error())
+				}
+				for _, expr := range test.Response.Assertions {
+					if _, err := parseAssertion(expr); err != nil {
+						return fmt.Errorf("%w: test '%s': %s", ErrInvalidSuite, test.Name, err.Error())
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// suiteMethods are the methods makeRequest knows.
+var suiteMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH"}
+
+// checkSuiteNames checks that the children of parent have unique names.
+func checkSuiteNames(kind, parent string, names []string) error {
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" {
+			return fmt.Errorf("%w: a %s of '%s' has no name", ErrInvalidSuite, kind, parent)
+		}
+		if seen[name] {
+			return fmt.Errorf("%w: '%s' has two %ss named '%s'", ErrInvalidSuite, parent, kind, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+func checkSuiteDependencies(kind, name string, dependsOn, siblings []string) error {
+	for _, dep := range dependsOn {
+		if !slices.Contains(siblings, dep) {
+			return fmt.Errorf("%w: %s '%s' depends on '%s', which is not its sibling", ErrInvalidSuite, kind, name, dep)
+		}
+	}
+	return nil
+}
+
+// checkSuitePoints refuses negative points, like the API does.
+func checkSuitePoints(kind, name string, points *float64) error {
+	if points != nil && !(*points >= 0) {
+		return fmt.Errorf("%w: %s '%s' has %v points, expected 0 or more", ErrInvalidSuite, kind, name, *points)
+	}
+	return nil
+}
+
+// ExportSuite writes a project tree, as loaded by LoadProject, down as a suite.
+func ExportSuite(proj *Project) *Suite {
+	suite := &Suite{Name: proj.Name, Due: proj.Due, Sections: []SuiteSection{}}
+	secNames := make(map[uint]string, len(proj.Sections))
+	for _, sec := range proj.Sections {
+		secNames[sec.ID] = sec.Name
+	}
+
+	for _, sec := range proj.Sections {
+		ss := SuiteSection{Name: sec.Name, Points: &sec.Points, DependsOn: dependencyNames(sec.DependsOnIDs, secNames)}
+		scnNames := make(map[uint]string, len(sec.Scenarios))
+		for _, scn := range sec.Scenarios {
+			scnNames[scn.ID] = scn.Name
+		}
+
+		for _, scn := range sec.Scenarios {
+			ssc := SuiteScenario{Name: scn.Name, Points: &scn.Points, DependsOn: dependencyNames(scn.DependsOnIDs, scnNames)}
+			testNames := make(map[uint]string, len(scn.Tests))
+			for _, test := range scn.Tests {
+				testNames[test.ID] = test.Name
+			}
+
+			for _, test := range scn.Tests {
+				st := SuiteTest{
+					Name:      test.Name,
+					Points:    &test.Points,
+					DependsOn: dependencyNames(test.DependsOnIDs, testNames),
+					Request: SuiteRequest{
+						Method: test.Request.Method,
+						Url:    test.Request.Url,
+						Body:   SuiteBody(test.Request.ReqBody),
+					},
+					Response: SuiteResponse{
+						StatusCode: test.Response.StatusCode,
+						Body:       SuiteBody(test.Response.ResBody),
+					},
+				}
+				for _, h := range test.Request.Headers {
+					st.Request.Headers = append(st.Request.Headers, SuiteHeader{Key: h.Key, Value: h.Value})
+				}
+				for _, h := range test.Response.Headers {
+					st.Response.Headers = append(st.Response.Headers, SuiteHeader{Key: h.Key, Value: h.Value})
+				}
+				for _, a := range test.Response.Assertions {
+					st.Response.Assertions = append(st.Response.Assertions, a.Expr)
+				}
+				st.Response.ObjectMode = test.Response.ObjectMode
+				st.Response.ArrayMode = test.Response.ArrayMode
+				for _, c := range test.Response.Comparisons {
+					st.Response.Comparisons = append(st.Response.Comparisons, SuiteComparison{Path: c.Path, ObjectMode: c.ObjectMode, ArrayMode: c.ArrayMode})
+				}
+				ssc.Tests = append(ssc.Tests, st)
+			}
+			ss.Scenarios = append(ss.Scenarios, ssc)
+		}
+		suite.Sections = append(suite.Sections, ss)
+	}
+	return suite
+}
+
+func dependencyNames(ids []uint, names map[uint]string) []string {
+	var deps []string
+	for _, id := range ids {
+		deps = append(deps, names[id])
+	}
+	return deps
+}
+
+// planSuite turns a suite into a plan, numbering its entries in file order as if they
+// had been stored, so that a suite can be graded without a database.
+func planSuite(suite *Suite) (*executionPlan, error) {
+	if err := suite.Validate(); err != nil {
+		return nil, err
+	}
+
+	var rows planRows
+	var lastID uint
+	nextID := func() uint {
+		lastID++
+		return lastID
+	}
+
+	secIDs := make(map[string]uint, len(suite.Sections))
+	for _, ss := range suite.Sections {
+		sec := Section{Name: ss.Name, Points: SuitePoints(ss.Points)}
+		sec.ID = nextID()
+		secIDs[ss.Name] = sec.ID
+		rows.Sections = append(rows.Sections, sec)
+	}
+	for _, ss := range suite.Sections {
+		for _, dep := range ss.DependsOn {
+			rows.SectionDeps = append(rows.SectionDeps, SectionDependency{SectionID: secIDs[ss.Name], DependsOnID: secIDs[dep]})
+		}
+
+		scnIDs := make(map[string]uint, len(ss.Scenarios))
+		for _, ssc := range ss.Scenarios {
+			scn := Scenario{Name: ssc.Name, Points: SuitePoints(ssc.Points), SectionID: secIDs[ss.Name]}
+			scn.ID = nextID()
+			scnIDs[ssc.Name] = scn.ID
+			rows.Scenarios = append(rows.Scenarios, scn)
+		}
+		for _, ssc := range ss.Scenarios {
+			for _, dep := range ssc.DependsOn {
+				rows.ScenarioDeps = append(rows.ScenarioDeps, ScenarioDependency{ScenarioID: scnIDs[ssc.Name], DependsOnID: scnIDs[dep]})
+			}
+
+			testIDs := make(map[string]uint, len(ssc.Tests))
+			for _, st := range ssc.Tests {
+				test := Test{
+					Name:       st.Name,
+					Points:     SuitePoints(st.Points),
+					ScenarioID: scnIDs[ssc.Name],
+					Request:    TRequest{Url: st.Request.Url, Method: st.Request.Method, ReqBody: string(st.Request.Body)},
+					Response:   st.Response.Model(),
+				}
+				test.ID = nextID()
+				testIDs[st.Name] = test.ID
+				rows.Tests = append(rows.Tests, test)
+				for _, h := range st.Request.Headers {
+					rows.RequestHeaders = append(rows.RequestHeaders, THeader{Key: h.Key, Value: h.Value, TestID: test.ID})
+				}
+				for _, h := range st.Response.Headers {
+					rows.ResponseHeaders = append(rows.ResponseHeaders, TResHeader{Key: h.Key, Value: h.Value, TestID: test.ID})
+				}
+				for _, expr := range st.Response.Assertions {
+					rows.Assertions = append(rows.Assertions, TAssertion{Expr: expr, TestID: test.ID})
+				}
+				for _, c := range test.Response.Comparisons {
+					c.TestID = test.ID
+					rows.Comparisons = append(rows.Comparisons, c)
+				}
+			}
+			for _, st := range ssc.Tests {
+				for _, dep := range st.DependsOn {
+					rows.TestDeps = append(rows.TestDeps, TestDependency{TestID: testIDs[st.Name], DependsOnID: testIDs[dep]})
+				}
+			}
+		}
+	}
+
+	plan := buildPlan(Project{Name: suite.Name, Due: suite.Due}, rows)
+	if err := plan.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSuite, err.Error())
+	}
+	return plan, nil
+}
+
+// SuitePoints returns the points of a suite entry, which default to 1 like in the API.
+func SuitePoints(points *float64) float64 {
+	if points == nil {
+		return 1
+	}
+	return *points
+}
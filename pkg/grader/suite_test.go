@@ -0,0 +1,57 @@
+package grader
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadSuitePoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		points string // of the section, scenario and test, in that order
+		err    string // part of the error, if the suite should be refused
+	}{
+		{name: "defaults"},
+		{name: "zero", points: "0,0,0"},
+		{name: "fractions", points: "0.5,2.25,10"},
+		{name: "negative section", points: "-1,1,1", err: "section 'Auth' has -1 points"},
+		{name: "negative scenario", points: "1,-0.5,1", err: "scenario 'Sign up' has -0.5 points"},
+		{name: "negative test", points: "1,1,-2", err: "test 'Create user' has -2 points"},
+		{name: "not a number", points: "1,1,.nan", err: "test 'Create user' has NaN points"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := []string{"", "", ""}
+			if tt.points != "" {
+				for i, p := range strings.Split(tt.points, ",") {
+					points[i] = "points: " + p
+				}
+			}
+			src := `
+name: Blog API
+sections:
+  - name: Auth
+    ` + points[0] + `
+    scenarios:
+      - name: Sign up
+        ` + points[1] + `
+        tests:
+          - name: Create user
+            ` + points[2] + `
+            request: {method: POST, url: /users}
+            response: {status_code: 201}
+`
+			_, err := ReadSuite(strings.NewReader(src))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidSuite) || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("error %v, want one about %q", err, tt.err)
+			}
+		})
+	}
+}
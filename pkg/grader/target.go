@@ -0,0 +1,165 @@
+package grader
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultBlockedNets are the addresses a grader never sends requests to unless they're
+// explicitly allowed: loopback, private and link-local ranges (cloud metadata services
+// live at 169.254.169.254), and other addresses that aren't a public service.
+var DefaultBlockedNets = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// maxRedirects matches what net/http allows by default.
+const maxRedirects = 10
+
+// TargetPolicy decides which hosts a grader may send requests to. Hosts are names
+// such as "api.example.com", or "*.example.com" for its subdomains.
+//
+// An allowed host is trusted whatever it resolves to; a blocked host is always
+// refused. Otherwise the address actually dialed decides, after DNS resolution, so a
+// name that resolves to a public address when checked and a private one when used
+// can't slip through: addresses and ports in AllowedAddrs and addresses in AllowedNets
+// pass, those in BlockedNets don't, and anything else does.
+type TargetPolicy struct {
+	AllowedHosts []string
+	BlockedHosts []string
+	// AllowedAddrs lets through a single port of an otherwise blocked address, e.g. the
+	// one a submission run on the grading machine listens on.
+	AllowedAddrs []netip.AddrPort
+	AllowedNets  []netip.Prefix
+	BlockedNets  []netip.Prefix
+	// Dial, if set, connects in place of dialer itself, e.g. from inside the network
+	// namespace a submission runs in. It must keep dialer's Control.
+	Dial func(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error)
+}
+
+// NewTargetPolicy blocks DefaultBlockedNets as well as the given hosts and networks.
+func NewTargetPolicy(allowedHosts, blockedHosts []string, allowedNets, blockedNets []netip.Prefix) *TargetPolicy {
+	return &TargetPolicy{
+		AllowedHosts: allowedHosts,
+		BlockedHosts: blockedHosts,
+		AllowedNets:  allowedNets,
+		BlockedNets:  append(append([]netip.Prefix{}, DefaultBlockedNets...), blockedNets...),
+	}
+}
+
+// checkHost tells whether host is allowed outright, blocked, or left to its addresses.
+func (p *TargetPolicy) checkHost(host string) (allowed bool, err error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if matchHost(p.BlockedHosts, host) {
+		return false, fmt.Errorf("%w: host %s is blocked", ErrTargetBlocked, host)
+	}
+	return matchHost(p.AllowedHosts, host), nil
+}
+
+func (p *TargetPolicy) checkAddr(addrPort netip.AddrPort) error {
+	addr := addrPort.Addr().Unmap()
+	for _, allowed := range p.AllowedAddrs {
+		if allowed.Addr().Unmap() == addr && allowed.Port() == addrPort.Port() {
+			return nil
+		}
+	}
+	for _, n := range p.AllowedNets {
+		if n.Contains(addr) {
+			return nil
+		}
+	}
+	for _, n := range p.BlockedNets {
+		if n.Contains(addr) {
+			return fmt.Errorf("%w: address %s is in blocked range %s", ErrTargetBlocked, addr, n)
+		}
+	}
+	return nil
+}
+
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// dialContext connects like net.Dialer, refusing addresses the policy blocks. The
+// check runs on the address being connected to, after resolution.
+func (p *TargetPolicy) dialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := p.checkHost(host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowed {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return p.checkAddr(ap)
+		}
+	}
+	if p.Dial != nil {
+		return p.Dial(ctx, dialer, network, address)
+	}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// checkRedirect re-checks every redirect the target answers with: it must stay on
+// http(s) and not lead to a blocked host. Its address is checked again when dialed.
+func (p *TargetPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("%w: redirect to a %s URL", ErrTargetBlocked, req.URL.Scheme)
+	}
+	_, err := p.checkHost(req.URL.Hostname())
+	return err
+}
+
+// apply makes client follow the policy. Proxies are ignored, since the policy has
+// to see the target's own address.
+func (p *TargetPolicy) apply(client *resty.Client) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = p.dialContext
+	client.SetTransport(transport)
+	client.SetRedirectPolicy(resty.RedirectPolicyFunc(p.checkRedirect))
+}
@@ -0,0 +1,177 @@
+package grader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newRedirectService answers /ok and redirects /redirect to its "to" query parameter.
+func newRedirectService(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true}`))
+	})
+	mux.HandleFunc("GET /redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// requestThrough grades a single GET of path against baseUrl under policy.
+func requestThrough(t *testing.T, policy *TargetPolicy, baseUrl, path string) TestResult {
+	t.Helper()
+	gd := NewGrader(baseUrl, 0)
+	gd.TargetPolicy = policy
+	result := gradeTestSuite(t, context.Background(), gd, `
+name: Target
+sections:
+  - name: Target
+    scenarios:
+      - name: Target
+        tests:
+          - name: get
+            request: {method: GET, url: "`+path+`"}
+            response: {status_code: 200}
+`)
+	return findTest(t, findScenario(t, findSection(t, result, "Target"), "Target"), "get")
+}
+
+func TestTargetPolicyRequests(t *testing.T) {
+	srv := newRedirectService(t)
+	port := strings.TrimPrefix(srv.URL, "http://127.0.0.1:")
+	localhost := "http://localhost:" + port
+	mapped := "http://[::ffff:127.0.0.1]:" + port
+	other := newRedirectService(t)
+
+	pinned := NewTargetPolicy(nil, nil, nil, nil)
+	pinned.AllowedAddrs = []netip.AddrPort{netip.MustParseAddrPort(srv.Listener.Addr().String())}
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	tests := []struct {
+		name    string
+		policy  *TargetPolicy
+		baseUrl string
+		path    string
+		message string // part of the failure's message, if the request should be refused
+	}{
+		{name: "no policy", baseUrl: srv.URL, path: "/ok"},
+		{name: "loopback", policy: NewTargetPolicy(nil, nil, nil, nil), baseUrl: srv.URL, path: "/ok", message: "address 127.0.0.1 is in blocked range 127.0.0.0/8"},
+		{name: "loopback by name", policy: NewTargetPolicy(nil, nil, nil, nil), baseUrl: localhost, path: "/ok", message: "is in blocked range"},
+		{name: "mapped loopback", policy: NewTargetPolicy(nil, nil, nil, nil), baseUrl: mapped, path: "/ok", message: "address 127.0.0.1 is in blocked range"},
+		{name: "allowed net", policy: NewTargetPolicy(nil, nil, loopback, nil), baseUrl: srv.URL, path: "/ok"},
+		{name: "allowed mapped", policy: NewTargetPolicy(nil, nil, loopback, nil), baseUrl: mapped, path: "/ok"},
+		{name: "allowed port", policy: pinned, baseUrl: srv.URL, path: "/ok"},
+		{name: "allowed port mapped", policy: pinned, baseUrl: mapped, path: "/ok"},
+		{name: "other port", policy: pinned, baseUrl: other.URL, path: "/ok", message: "is in blocked range"},
+		{name: "allowed host", policy: NewTargetPolicy([]string{"LOCALHOST"}, nil, nil, nil), baseUrl: localhost, path: "/ok"},
+		{name: "allowed address", policy: NewTargetPolicy([]string{"127.0.0.1"}, nil, nil, nil), baseUrl: srv.URL, path: "/ok"},
+		{name: "blocked host", policy: NewTargetPolicy(nil, []string{"localhost"}, loopback, nil), baseUrl: localhost, path: "/ok", message: "host localhost is blocked"},
+		{name: "blocked net", policy: NewTargetPolicy(nil, nil, nil, []netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")}), baseUrl: srv.URL, path: "/ok", message: "blocked range"},
+		{
+			name:   "redirect to a blocked host",
+			policy: NewTargetPolicy([]string{"127.0.0.1"}, []string{"*.internal"}, nil, nil), baseUrl: srv.URL,
+			path:    "/redirect?to=" + url.QueryEscape("http://db.internal/"),
+			message: "host db.internal is blocked",
+		},
+		{
+			name:   "redirect to a blocked address",
+			policy: NewTargetPolicy([]string{"127.0.0.1"}, nil, nil, nil), baseUrl: srv.URL,
+			path:    "/redirect?to=" + url.QueryEscape(localhost+"/ok"),
+			message: "is in blocked range",
+		},
+		{
+			name:   "redirect to another port",
+			policy: pinned, baseUrl: srv.URL,
+			path:    "/redirect?to=" + url.QueryEscape(other.URL+"/ok"),
+			message: "is in blocked range",
+		},
+		{
+			name:   "redirect to another scheme",
+			policy: NewTargetPolicy([]string{"127.0.0.1"}, nil, nil, nil), baseUrl: srv.URL,
+			path:    "/redirect?to=" + url.QueryEscape("file:///etc/passwd"),
+			message: "redirect to a file URL",
+		},
+		{
+			name:   "redirect to an allowed host",
+			policy: NewTargetPolicy([]string{"127.0.0.1", "localhost"}, nil, nil, nil), baseUrl: srv.URL,
+			path: "/redirect?to=" + url.QueryEscape(localhost+"/ok"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := requestThrough(t, tt.policy, tt.baseUrl, tt.path)
+			if tt.message == "" {
+				if result.Status != StatusPassed {
+					t.Fatalf("%s (%s): %s", result.Status, result.Reason, result.Message)
+				}
+				return
+			}
+			if result.Status != StatusFailed || result.Reason != ReasonTargetBlocked || !strings.Contains(result.Message, tt.message) {
+				t.Fatalf("%s (%s): %s; want refused for %q", result.Status, result.Reason, result.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestTargetPolicyCheckAddr(t *testing.T) {
+	policy := NewTargetPolicy(nil, nil, []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}, []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")})
+	policy.AllowedAddrs = []netip.AddrPort{netip.MustParseAddrPort("127.0.0.1:8080")}
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{addr: "93.184.216.34:80"},
+		{addr: "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		{addr: "127.0.0.1:80", blocked: true},
+		{addr: "127.0.0.1:8080"},
+		{addr: "[::ffff:127.0.0.1]:8080"},
+		{addr: "127.0.0.2:8080", blocked: true},
+		{addr: "[::1]:8080", blocked: true},
+		{addr: "[::ffff:127.0.0.1]:80", blocked: true},
+		{addr: "[::ffff:169.254.169.254]:80", blocked: true},
+		{addr: "169.254.169.254:80", blocked: true},
+		{addr: "192.168.1.1:80", blocked: true},
+		{addr: "[fd00::1]:80", blocked: true},
+		{addr: "10.2.0.1:80", blocked: true},
+		{addr: "10.1.0.1:80"},
+		{addr: "[::ffff:10.1.0.1]:80"},
+		{addr: "203.0.113.7:80", blocked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := policy.checkAddr(netip.MustParseAddrPort(tt.addr))
+			if tt.blocked != errors.Is(err, ErrTargetBlocked) {
+				t.Errorf("got %v, want blocked: %v", err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	patterns := []string{"api.example.com", "*.Internal"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "api.example.com", want: true},
+		{host: "www.example.com"},
+		{host: "example.com"},
+		{host: "db.internal", want: true},
+		{host: "a.b.internal", want: true},
+		{host: "internal"},
+		{host: "notinternal"},
+	}
+	for _, tt := range tests {
+		if got := matchHost(patterns, tt.host); got != tt.want {
+			t.Errorf("matchHost(%s) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
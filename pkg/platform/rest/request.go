@@ -0,0 +1,20 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LimitBody makes reading more than n bytes of the request body fail with an
+// *http.MaxBytesError, which TooLarge tells apart from other errors.
+func LimitBody(c *gin.Context, n int64) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+}
+
+// TooLarge tells if err comes from reading past the limit LimitBody set.
+func TooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
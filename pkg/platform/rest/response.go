@@ -26,6 +26,14 @@ func RespondCreated(c *gin.Context, data interface{}) {
 	})
 }
 
+// RespondAccepted sends a JSON response for work that was queued to be done later.
+func RespondAccepted(c *gin.Context, data interface{}) {
+	c.JSON(202, Response{
+		Status: "success",
+		Data:   data,
+	})
+}
+
 // RespondNoContent sends a 204 No Content response.
 func RespondNoContent(c *gin.Context) {
 	c.Status(204)
@@ -0,0 +1,13 @@
+// Package spreadsheet helps write CSV files that people open in spreadsheets.
+package spreadsheet
+
+import "strings"
+
+// Text prefixes s with a quote if a spreadsheet would read it as a formula, so that
+// names and the like from users can't run one.
+func Text(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
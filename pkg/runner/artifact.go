@@ -0,0 +1,244 @@
+package runner
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the kind of file a student uploaded.
+type Format string
+
+const (
+	FormatBinary Format = "binary" // a Linux executable, run as it is
+	FormatZip    Format = "zip"
+	FormatTar    Format = "tar"
+	FormatTarGz  Format = "tar.gz"
+)
+
+// binaryName is what an uploaded binary is saved as in its scratch directory.
+const binaryName = "app"
+
+// maxArchiveFiles bounds the number of entries extracted from an archive.
+const maxArchiveFiles = 10000
+
+// Detect tells the format of an uploaded file from its first bytes.
+func Detect(path string) (Format, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return FormatBinary, nil
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return FormatZip, nil
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return FormatTarGz, nil
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return FormatTar, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// unpack puts an uploaded file into dir and returns the directory to start it in and
+// whether it's a binary. Archives holding a single top-level directory are started inside it.
+func unpack(path, dir string, maxSize int64) (string, bool, error) {
+	format, err := Detect(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	switch format {
+	case FormatBinary:
+		return dir, true, copyBinary(path, filepath.Join(dir, binaryName))
+	case FormatZip:
+		err = extractZip(path, dir, maxSize)
+	case FormatTar, FormatTarGz:
+		err = extractTar(path, dir, format == FormatTarGz, maxSize)
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false, err
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name()), false, nil
+	}
+	return dir, false, nil
+}
+
+func copyBinary(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func extractZip(path, dir string, maxSize int64) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer r.Close()
+
+	if len(r.File) > maxArchiveFiles {
+		return fmt.Errorf("%w: more than %d files", ErrArchiveTooLarge, maxArchiveFiles)
+	}
+	budget := extractBudget(maxSize)
+	for _, f := range r.File {
+		mode := f.Mode()
+		if mode&os.ModeSymlink != 0 || !(mode.IsDir() || mode.IsRegular()) {
+			continue
+		}
+		target, err := entryPath(dir, f.Name)
+		if err != nil {
+			return err
+		}
+		if mode.IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		err = writeFile(target, rc, mode, budget)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTar(path, dir string, gzipped bool, maxSize int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var src io.Reader = f
+	if gzipped {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	tr := tar.NewReader(src)
+	budget := extractBudget(maxSize)
+	for files := 0; ; files++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if files >= maxArchiveFiles {
+			return fmt.Errorf("%w: more than %d files", ErrArchiveTooLarge, maxArchiveFiles)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			target, err := entryPath(dir, hdr.Name)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			target, err := entryPath(dir, hdr.Name)
+			if err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, hdr.FileInfo().Mode(), budget); err != nil {
+				return err
+			}
+		}
+		// Links, devices and the like are left out.
+	}
+}
+
+// entryPath returns where an archive entry goes in dir, refusing names that would
+// land outside of it.
+func entryPath(dir, name string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if target != dir && !strings.HasPrefix(target, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return target, nil
+}
+
+// extractBudget is what's left to extract of maxSize, nil when there's no limit.
+func extractBudget(maxSize int64) *int64 {
+	if maxSize == 0 {
+		return nil
+	}
+	return &maxSize
+}
+
+// writeFile writes an extracted file, taking its size off budget unless it's nil. Only
+// the executable bits of mode are kept.
+func writeFile(target string, r io.Reader, mode os.FileMode, budget *int64) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644|mode.Perm()&0o111)
+	if err != nil {
+		return err
+	}
+
+	if budget == nil {
+		_, err = io.Copy(out, r)
+	} else {
+		var n int64
+		n, err = io.Copy(out, io.LimitReader(r, *budget+1))
+		*budget -= n
+		if err == nil && *budget < 0 {
+			err = ErrArchiveTooLarge
+		}
+	}
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,279 @@
+package runner
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// entry is a file, directory or link put into a test archive.
+type entry struct {
+	name    string
+	body    string
+	mode    os.FileMode
+	symlink string // the link's target, if it's a symlink
+	link    string // the link's target, if it's a hard link; tar only
+}
+
+func writeZip(t *testing.T, path string, entries []entry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		if e.link != "" {
+			continue
+		}
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		switch {
+		case e.symlink != "":
+			hdr.SetMode(os.ModeSymlink | 0o777)
+			body = e.symlink
+		case e.mode.IsDir():
+			hdr.SetMode(e.mode)
+		default:
+			hdr.SetMode(e.mode | 0o644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTar(t *testing.T, path string, gzipped bool, entries []entry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var w io.Writer = f
+	if gzipped {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: int64(e.mode.Perm() | 0o644)}
+		switch {
+		case e.symlink != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, e.symlink
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeLink, e.link
+		case e.mode.IsDir():
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0o755
+		default:
+			hdr.Typeflag, hdr.Size = tar.TypeReg, int64(len(e.body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(tw, e.body)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEntryPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "submission")
+	tests := []struct {
+		name string
+		want string
+		err  bool
+	}{
+		{name: "app.js", want: filepath.Join(dir, "app.js")},
+		{name: "src/main.go", want: filepath.Join(dir, "src", "main.go")},
+		{name: "src/../main.go", want: filepath.Join(dir, "main.go")},
+		{name: "./", want: dir},
+		// Absolute names are taken as relative to the archive.
+		{name: "/etc/passwd", want: filepath.Join(dir, "etc", "passwd")},
+		{name: "../evil", err: true},
+		{name: "src/../../evil", err: true},
+		{name: "..", err: true},
+		{name: "../submission-2/evil", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := entryPath(dir, tt.name)
+			if tt.err {
+				if !errors.Is(err, ErrUnsafePath) {
+					t.Fatalf("got %q, %v; want an unsafe path", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	manyFiles := make([]entry, maxArchiveFiles+1)
+	for i := range manyFiles {
+		manyFiles[i] = entry{name: fmt.Sprintf("f%d", i)}
+	}
+
+	tests := []struct {
+		name     string
+		entries  []entry
+		maxSize  int64
+		files    map[string]string // what's extracted, relative to the returned directory
+		missing  []string          // what isn't, relative to the scratch directory
+		inside   string            // the top-level directory it's started in, if any
+		err      error
+		tarOnly  bool   // zip has no hard links
+		execFile string // a file that should stay executable
+	}{
+		{
+			name:     "files",
+			entries:  []entry{{name: "start.sh", body: "#!/bin/sh", mode: 0o755}, {name: "src/", mode: os.ModeDir}, {name: "src/app.js", body: "app"}},
+			maxSize:  1024,
+			files:    map[string]string{"start.sh": "#!/bin/sh", "src/app.js": "app"},
+			execFile: "start.sh",
+		},
+		{
+			name:    "single directory",
+			entries: []entry{{name: "project/", mode: os.ModeDir}, {name: "project/start.sh", body: "run"}},
+			files:   map[string]string{"start.sh": "run"},
+			inside:  "project",
+		},
+		{
+			name:    "traversal",
+			entries: []entry{{name: "ok.txt", body: "ok"}, {name: "../evil.txt", body: "evil"}},
+			err:     ErrUnsafePath,
+			missing: []string{"../evil.txt"},
+		},
+		{
+			name:    "absolute name",
+			entries: []entry{{name: "/tmp/evil.txt", body: "evil"}, {name: "app.js", body: "app"}},
+			files:   map[string]string{"tmp/evil.txt": "evil"},
+		},
+		{
+			name:    "symlinks",
+			entries: []entry{{name: "passwd", symlink: "/etc/passwd"}, {name: "up", symlink: ".."}, {name: "app.js", body: "app"}},
+			files:   map[string]string{"app.js": "app"},
+			missing: []string{"passwd", "up"},
+		},
+		{
+			name:    "hard links",
+			entries: []entry{{name: "passwd", link: "/etc/passwd"}, {name: "app.js", body: "app"}},
+			files:   map[string]string{"app.js": "app"},
+			missing: []string{"passwd"},
+			tarOnly: true,
+		},
+		{
+			name:    "within budget",
+			entries: []entry{{name: "a", body: "12345"}, {name: "b", body: "12345"}},
+			maxSize: 10,
+			files:   map[string]string{"a": "12345", "b": "12345"},
+		},
+		{
+			name:    "over budget",
+			entries: []entry{{name: "a", body: "12345"}, {name: "b", body: "123456"}},
+			maxSize: 10,
+			err:     ErrArchiveTooLarge,
+		},
+		{
+			name:    "no budget",
+			entries: []entry{{name: "a", body: "12345"}},
+			files:   map[string]string{"a": "12345"},
+		},
+		{
+			name:    "too many files",
+			entries: manyFiles,
+			err:     ErrArchiveTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		for _, format := range []Format{FormatZip, FormatTar, FormatTarGz} {
+			if format == FormatZip && tt.tarOnly {
+				continue
+			}
+			t.Run(tt.name+" "+string(format), func(t *testing.T) {
+				tmp := t.TempDir()
+				archive := filepath.Join(tmp, "upload")
+				if format == FormatZip {
+					writeZip(t, archive, tt.entries)
+				} else {
+					writeTar(t, archive, format == FormatTarGz, tt.entries)
+				}
+				dir := filepath.Join(tmp, "scratch")
+				if err := os.Mkdir(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+
+				workDir, binary, err := unpack(archive, dir, tt.maxSize)
+				for _, name := range tt.missing {
+					if _, err := os.Lstat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+						t.Errorf("%s was extracted", name)
+					}
+				}
+				if tt.err != nil {
+					if !errors.Is(err, tt.err) {
+						t.Fatalf("got %v, want %v", err, tt.err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if binary || workDir != filepath.Join(dir, tt.inside) {
+					t.Errorf("got %q, %v; want to start in %q", workDir, binary, tt.inside)
+				}
+				for name, body := range tt.files {
+					got, err := os.ReadFile(filepath.Join(workDir, name))
+					if err != nil || string(got) != body {
+						t.Errorf("%s: got %q, %v; want %q", name, got, err, body)
+					}
+				}
+				if tt.execFile != "" {
+					info, err := os.Stat(filepath.Join(workDir, tt.execFile))
+					if err != nil || info.Mode().Perm()&0o100 == 0 {
+						t.Errorf("%s isn't executable: %v, %v", tt.execFile, info, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestUnpackBinary(t *testing.T) {
+	tmp := t.TempDir()
+	upload := filepath.Join(tmp, "upload")
+	if err := os.WriteFile(upload, []byte("\x7fELF rest of the binary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	workDir, binary, err := unpack(upload, tmp, 0)
+	if err != nil || !binary || workDir != tmp {
+		t.Fatalf("got %q, %v, %v", workDir, binary, err)
+	}
+	info, err := os.Stat(filepath.Join(tmp, binaryName))
+	if err != nil || info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("binary isn't executable: %v, %v", info, err)
+	}
+
+	if err := os.WriteFile(upload, []byte("#!/bin/sh\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := unpack(upload, tmp, 0); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("got %v for a script, want %v", err, ErrUnsupportedFormat)
+	}
+}
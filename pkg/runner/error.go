@@ -0,0 +1,28 @@
+package runner
+
+import "errors"
+
+var (
+	ErrUnsupportedFormat = errors.New("not a zip or tar archive or a Linux executable")
+	ErrInvalidArchive    = errors.New("invalid archive")
+	ErrArchiveTooLarge   = errors.New("archive is too large")
+	ErrUnsafePath        = errors.New("archive entry outside of the archive")
+	ErrNotHealthy        = errors.New("service didn't become healthy")
+	ErrExited            = errors.New("service exited")
+	ErrNotIsolated       = errors.New("submissions can't run as root")
+)
+
+// StartError is returned when a submission couldn't be started. It keeps what the
+// submission printed, which usually tells why.
+type StartError struct {
+	Err    error
+	Output string
+}
+
+func (e *StartError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
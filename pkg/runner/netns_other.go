@@ -0,0 +1,19 @@
+//go:build !linux
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+)
+
+func startInNetns(cmd *exec.Cmd) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func dialInNetns(ctx context.Context, ns *os.File, dialer *net.Dialer, network, address string) (net.Conn, error) {
+	return nil, errors.ErrUnsupported
+}
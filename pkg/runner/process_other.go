@@ -0,0 +1,16 @@
+//go:build !unix
+
+package runner
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func setProcessAttrs(cmd *exec.Cmd, cfg Config) error {
+	return errors.ErrUnsupported
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return errors.ErrUnsupported
+}
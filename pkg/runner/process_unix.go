@@ -0,0 +1,29 @@
+//go:build unix
+
+package runner
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessAttrs starts cmd in a process group of its own, as cfg's user and group
+// with no supplementary groups.
+func setProcessAttrs(cmd *exec.Cmd, cfg Config) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:    true,
+		Credential: &syscall.Credential{Uid: cfg.UID, Gid: cfg.GID},
+	}
+	return nil
+}
+
+// killProcessGroup kills the process group cmd leads. A group with nothing left in it
+// is fine.
+func killProcessGroup(cmd *exec.Cmd) error {
+	err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	if errors.Is(err, syscall.ESRCH) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,307 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Config says how submissions are started and what they may use.
+type Config struct {
+	// Command starts an extracted archive, e.g. "./start.sh". It runs with sh in the
+	// archive's directory and must serve HTTP on $PORT. Binaries are run as they are.
+	Command string
+	// HealthPath is polled until the service answers with anything but a 5xx.
+	HealthPath   string
+	StartTimeout time.Duration
+	// WallTime bounds how long the service may run at all, CPUTime how much CPU it may
+	// use and Memory, in bytes, how much data it may keep: RLIMIT_DATA, which counts
+	// writable private memory but not address space that's only reserved, as V8 and the
+	// JVM do at startup. Zero means no limit.
+	WallTime time.Duration
+	CPUTime  time.Duration
+	Memory   int64
+	// MaxExtract bounds the total size of the files extracted from an archive. Zero
+	// means no limit.
+	MaxExtract int64
+	// MaxOutput bounds how much of what the service prints is kept.
+	MaxOutput int
+	// ScratchDir is where submissions are unpacked; empty means the system's temp dir.
+	ScratchDir string
+	// UID and GID are the unprivileged user and group the service runs as. Root can't
+	// be used: switching to them, like giving the service a network namespace of its
+	// own, needs the grader to run as root.
+	UID uint32
+	GID uint32
+}
+
+// waitDelay bounds how long Wait keeps reading the service's output once it exited, in
+// case something it started left its process group and holds on to the pipe.
+const waitDelay = 5 * time.Second
+
+// stopTimeout bounds how long Stop waits for the service to be reaped.
+const stopTimeout = 10 * time.Second
+
+// Process is a submission running on the local machine, listening on the loopback of
+// its own network namespace.
+type Process struct {
+	cmd    *exec.Cmd
+	dir    string
+	port   int
+	netns  *os.File
+	output *outputBuffer
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Start unpacks the uploaded artifact into a scratch directory, starts it on a free
+// port and waits until it's healthy. The process runs in its own process group so that
+// Stop takes down whatever it started, and is killed once ctx is done or its wall time
+// is up.
+//
+// The service runs as cfg's user, which owns nothing but the scratch directory, and in
+// a network namespace with nothing but loopback in it: it can't reach the network,
+// and only Dial reaches it. This needs Linux and a grader running as root.
+func Start(ctx context.Context, artifact string, cfg Config) (*Process, error) {
+	if cfg.UID == 0 || cfg.GID == 0 {
+		return nil, ErrNotIsolated
+	}
+	dir, err := os.MkdirTemp(cfg.ScratchDir, "submission-*")
+	if err != nil {
+		return nil, err
+	}
+	p := &Process{
+		dir:    dir,
+		output: &outputBuffer{max: cfg.MaxOutput},
+		done:   make(chan struct{}),
+	}
+
+	workDir, binary, err := unpack(artifact, dir, cfg.MaxExtract)
+	if err != nil {
+		p.Stop()
+		return nil, &StartError{Err: err}
+	}
+	command := cfg.Command
+	if binary {
+		command = "./" + binaryName
+	}
+	if err := chownTree(dir, cfg.UID, cfg.GID); err != nil {
+		p.Stop()
+		return nil, err
+	}
+
+	if p.port, err = freePort(); err != nil {
+		p.Stop()
+		return nil, err
+	}
+
+	p.cmd = exec.Command("sh", "-c", limitScript(cfg)+"exec "+command)
+	p.cmd.Dir = workDir
+	// The grader's environment holds its secrets, so the service gets a clean one.
+	p.cmd.Env = []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=" + workDir,
+		"HOST=127.0.0.1",
+		"PORT=" + strconv.Itoa(p.port),
+	}
+	p.cmd.Stdout = p.output
+	p.cmd.Stderr = p.output
+	p.cmd.WaitDelay = waitDelay
+	if err := setProcessAttrs(p.cmd, cfg); err != nil {
+		p.Stop()
+		return nil, &StartError{Err: err}
+	}
+	if p.netns, err = startInNetns(p.cmd); err != nil {
+		p.Stop()
+		return nil, &StartError{Err: err}
+	}
+	go func() {
+		p.cmd.Wait()
+		close(p.done)
+	}()
+
+	cancel := context.CancelFunc(func() {})
+	if cfg.WallTime > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cfg.WallTime)
+	}
+	go func() {
+		defer cancel()
+		select {
+		case <-ctx.Done():
+			p.kill()
+		case <-p.done:
+		}
+	}()
+
+	if err := p.waitHealthy(ctx, cfg); err != nil {
+		p.Stop()
+		return nil, &StartError{Err: err, Output: p.Output()}
+	}
+	return p, nil
+}
+
+// BaseUrl is where the service is listening.
+func (p *Process) BaseUrl() string {
+	return fmt.Sprintf("http://127.0.0.1:%d", p.port)
+}
+
+// Addr is the address the service listens on.
+func (p *Process) Addr() netip.AddrPort {
+	return netip.AddrPortFrom(netip.AddrFrom4([4]byte{127, 0, 0, 1}), uint16(p.port))
+}
+
+// Dial connects with dialer from inside the service's network namespace, where
+// BaseUrl leads to the service and nothing else is reachable.
+func (p *Process) Dial(ctx context.Context, dialer *net.Dialer, network, address string) (net.Conn, error) {
+	return dialInNetns(ctx, p.netns, dialer, network, address)
+}
+
+// Output returns what the service printed so far, stdout and stderr interleaved.
+func (p *Process) Output() string {
+	return p.output.String()
+}
+
+// Stop kills the service along with every process it started and removes its
+// scratch directory. It's safe to call more than once.
+func (p *Process) Stop() {
+	p.once.Do(func() {
+		if p.cmd != nil && p.cmd.Process != nil {
+			p.kill()
+			select {
+			case <-p.done:
+			case <-time.After(stopTimeout):
+			}
+		}
+		if p.netns != nil {
+			p.netns.Close()
+		}
+		os.RemoveAll(p.dir)
+	})
+}
+
+// kill kills the service's process group, even if the service itself already exited:
+// what it left behind in the group, e.g. a daemon, would keep running otherwise. The
+// group's ID isn't reused while anything is left in it.
+func (p *Process) kill() {
+	if err := killProcessGroup(p.cmd); err == nil {
+		return
+	}
+	select {
+	case <-p.done:
+	default:
+		p.cmd.Process.Kill()
+	}
+}
+
+// waitHealthy polls the health path until the service answers, exits or runs out of time.
+func (p *Process) waitHealthy(ctx context.Context, cfg Config) error {
+	if cfg.StartTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.StartTimeout)
+		defer cancel()
+	}
+
+	dialer := &net.Dialer{}
+	client := &http.Client{
+		Transport: &http.Transport{DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return p.Dial(ctx, dialer, network, address)
+		}},
+		Timeout: time.Second,
+	}
+	defer client.CloseIdleConnections()
+	url := p.BaseUrl() + "/" + strings.TrimPrefix(cfg.HealthPath, "/")
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 500 {
+				return nil
+			}
+		}
+
+		select {
+		case <-p.done:
+			return fmt.Errorf("%w: %s", ErrExited, p.cmd.ProcessState)
+		case <-ctx.Done():
+			return fmt.Errorf("%w within %s", ErrNotHealthy, cfg.StartTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// limitScript sets the resource limits in the shell that execs the service, so that
+// they apply to it and everything it starts.
+func limitScript(cfg Config) string {
+	var script strings.Builder
+	if cfg.CPUTime > 0 {
+		fmt.Fprintf(&script, "ulimit -t %d && ", int64((cfg.CPUTime+time.Second-1)/time.Second))
+	}
+	if cfg.Memory > 0 {
+		fmt.Fprintf(&script, "ulimit -d %d && ", (cfg.Memory+1023)/1024)
+	}
+	return script.String()
+}
+
+// chownTree gives everything in dir to uid and gid.
+func chownTree(dir string, uid, gid uint32) error {
+	return filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, int(uid), int(gid))
+	})
+}
+
+// freePort finds a loopback port nobody is listening on.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// outputBuffer keeps the first max bytes written to it.
+type outputBuffer struct {
+	mu        sync.Mutex
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.max - len(b.buf); b.max > 0 && len(p) > room {
+		b.buf = append(b.buf, p[:max(room, 0)]...)
+		b.truncated = true
+	} else {
+		b.buf = append(b.buf, p...)
+	}
+	return len(p), nil
+}
+
+func (b *outputBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.truncated {
+		return string(b.buf) + "\n[output truncated]"
+	}
+	return string(b.buf)
+}